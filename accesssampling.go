@@ -0,0 +1,78 @@
+package log
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SampleRateKey is the field key for the rate an access log entry was
+// sampled at, see WithAccessSampling.
+const SampleRateKey = "sample_rate"
+
+// WithAccessSampling logs only a share of requests by status class, so
+// high traffic services can keep every error without logging every
+// healthy request. rates maps the hundreds digit of the status to the
+// share logged, from 0 to 1:
+//
+//	log.WithAccessSampling(map[int]float64{2: 0.01, 4: 0.1}, time.Minute)
+//
+// Classes not in rates are all logged, as are slow requests, see
+// WithSlowThreshold. Sampled entries have a sample_rate field. Every
+// interval, an entry with the number of requests dropped for each class
+// since the last one is logged at the info level, if the logger logs
+// that level.
+func WithAccessSampling(rates map[int]float64, interval time.Duration) HTTPOption {
+	return func(o *httpOptions) {
+		s := &accessSampler{rates: map[int]float64{}, interval: interval, dropped: map[int]int{}}
+		for class, r := range rates {
+			s.rates[class] = r
+		}
+		o.sampler = s
+	}
+}
+
+type accessSampler struct {
+	rates    map[int]float64
+	interval time.Duration
+
+	mu      sync.Mutex
+	last    time.Time
+	dropped map[int]int
+}
+
+// sample reports whether a request with the status should be logged,
+// and the rate it was sampled at. It logs the dropped counts through l
+// when they are due.
+func (s *accessSampler) sample(l *Logger, status int) (bool, float64) {
+	class := status / 100
+	rate, ok := s.rates[class]
+	keep := !ok || rate >= 1 || (rate > 0 && rand.Float64() < rate)
+	now := time.Now()
+	s.mu.Lock()
+	if s.last.IsZero() {
+		s.last = now
+	}
+	if !keep {
+		s.dropped[class]++
+	}
+	var fs []Field
+	if now.Sub(s.last) >= s.interval {
+		for class, n := range s.dropped {
+			fs = append(fs, Int(fmt.Sprintf("dropped_%dxx", class), n))
+		}
+		sort.Slice(fs, func(i, j int) bool { return fs[i].key < fs[j].key })
+		s.dropped = map[int]int{}
+		s.last = now
+	}
+	s.mu.Unlock()
+	if len(fs) > 0 && l.enabled(InfoLevel) {
+		l.writeFields(InfoLevel, "access log sampling", fs)
+	}
+	if !ok || rate >= 1 {
+		rate = 1
+	}
+	return keep, rate
+}
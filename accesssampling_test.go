@@ -0,0 +1,92 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var statusHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	code, _ := strconv.Atoi(r.URL.Query().Get("status"))
+	w.WriteHeader(code)
+})
+
+func TestAccessSamplingRates(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	l := NewLogger("http", false)
+	l.SetDebug(true)
+	h := HTTPHandler(statusHandler, l, nil, WithAccessSampling(map[int]float64{2: 0, 4: 0.5}, time.Hour))
+
+	const n = 400
+	for _, status := range []int{200, 404, 500} {
+		for i := 0; i < n; i++ {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?status="+strconv.Itoa(status), nil))
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	count := map[string]int{}
+	for _, line := range lines {
+		for _, s := range []string{"[200]", "[404]", "[500]"} {
+			if strings.Contains(line, s) {
+				count[s]++
+				if s == "[404]" && !strings.Contains(line, "sample_rate=0.5") {
+					t.Errorf("sampled entry without its rate: %q", line)
+				}
+				if s == "[500]" && strings.Contains(line, "sample_rate") {
+					t.Errorf("unsampled entry with a rate: %q", line)
+				}
+			}
+		}
+	}
+	if count["[200]"] != 0 {
+		t.Errorf("logged %d of %d requests sampled at 0", count["[200]"], n)
+	}
+	// The chance of falling outside this range is far below 1e-9.
+	if c := count["[404]"]; c < n/4 || c > 3*n/4 {
+		t.Errorf("logged %d of %d requests sampled at 0.5", c, n)
+	}
+	if count["[500]"] != n {
+		t.Errorf("logged %d of %d 5xx requests, want all", count["[500]"], n)
+	}
+}
+
+func TestAccessSamplingSummary(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	l := NewLogger("http", false)
+
+	var o httpOptions
+	WithAccessSampling(map[int]float64{2: 0, 4: 0}, 20*time.Millisecond)(&o)
+	s := o.sampler
+	for i := 0; i < 3; i++ {
+		s.sample(l, 200)
+	}
+	s.sample(l, 404)
+	if out := buf.String(); out != "" {
+		t.Fatalf("summary logged before the interval: %q", out)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if keep, rate := s.sample(l, 500); !keep || rate != 1 {
+		t.Errorf("sample(500) = %v, %v, want true, 1", keep, rate)
+	}
+	want := "http    |  access log sampling dropped_2xx=3 dropped_4xx=1\n"
+	if out := buf.String(); out != want {
+		t.Errorf("summary = %q, want %q", out, want)
+	}
+
+	// The counts start over with each summary, and the summary follows
+	// the logger's level.
+	keepSettings(t)
+	SetLevel(WarnLevel)
+	time.Sleep(25 * time.Millisecond)
+	s.sample(l, 200)
+	if out := buf.String(); out != want {
+		t.Errorf("summary logged at the warn level: %q", strings.TrimPrefix(out, want))
+	}
+}
@@ -0,0 +1,73 @@
+package log
+
+import (
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// BuildInfoMode controls when the VCS build information is attached
+// to entries.
+type BuildInfoMode int32
+
+const (
+	// BuildInfoOff never attaches build information. This is the
+	// default.
+	BuildInfoOff BuildInfoMode = iota
+	// BuildInfoFirst attaches build information to the first entry
+	// written by the process, after suppression and sampling.
+	BuildInfoFirst
+	// BuildInfoEvery attaches build information to every entry.
+	BuildInfoEvery
+)
+
+var (
+	buildInfoMode   int32
+	buildInfoLogged int32
+	buildInfoOnce   sync.Once
+	buildInfoFields []Field
+)
+
+// SetBuildInfo sets when the vcs.revision and vcs.time from the
+// binary's build information are attached to entries, so a log stream
+// identifies the build that produced it.
+//
+// Nothing is attached if the binary was built without VCS stamping.
+func SetBuildInfo(mode BuildInfoMode) {
+	atomic.StoreInt32(&buildInfoMode, int32(mode))
+}
+
+// addBuildInfo puts the build information in front of e's fields, so
+// the entry's own fields override it. It is called by emit once e is
+// sure to be written, so with BuildInfoFirst an entry that is dropped
+// can't use up the one attachment.
+func addBuildInfo(e *entry) {
+	switch BuildInfoMode(atomic.LoadInt32(&buildInfoMode)) {
+	case BuildInfoEvery:
+	case BuildInfoFirst:
+		if !atomic.CompareAndSwapInt32(&buildInfoLogged, 0, 1) {
+			return
+		}
+	default:
+		return
+	}
+	buildInfoOnce.Do(readBuildInfo)
+	if len(buildInfoFields) == 0 {
+		return
+	}
+	fs := make([]Field, 0, len(buildInfoFields)+len(e.fields))
+	e.fields = append(append(fs, buildInfoFields...), e.fields...)
+}
+
+func readBuildInfo() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision", "vcs.time":
+			buildInfoFields = append(buildInfoFields, Any(s.Key, s.Value))
+		}
+	}
+}
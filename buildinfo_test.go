@@ -0,0 +1,59 @@
+package log
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// fakeBuildInfo makes the build information vcs.revision=abc for the
+// test, as test binaries are built without VCS stamping.
+func fakeBuildInfo(t *testing.T, mode BuildInfoMode) {
+	buildInfoOnce.Do(readBuildInfo)
+	old := buildInfoFields
+	buildInfoFields = []Field{Any("vcs.revision", "abc")}
+	atomic.StoreInt32(&buildInfoLogged, 0)
+	SetBuildInfo(mode)
+	t.Cleanup(func() {
+		SetBuildInfo(BuildInfoOff)
+		atomic.StoreInt32(&buildInfoLogged, 0)
+		buildInfoFields = old
+	})
+}
+
+func TestBuildInfoFirstWrittenEntry(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	fakeBuildInfo(t, BuildInfoFirst)
+	KeepRecent(10, DebugLevel)
+	t.Cleanup(func() { KeepRecent(0, DebugLevel) })
+	SetSuppressions([]Suppression{{Message: "noise", Drop: true}})
+	t.Cleanup(func() { SetSuppressions(nil) })
+
+	l := NewLogger("build", false)
+	l.Debug("kept but not logged")
+	l.Info("noise")
+	l.Info("first")
+	l.Info("second")
+
+	want := "build   |  first vcs.revision=abc\nbuild   |  second\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestBuildInfoEvery(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	fakeBuildInfo(t, BuildInfoEvery)
+
+	l := NewLogger("build", false)
+	l.Info("one")
+	l.Info("two")
+	l.With(Str("vcs.revision", "mine")).Info("own")
+	want := "build   |  one vcs.revision=abc\nbuild   |  two vcs.revision=abc\nbuild   |  own vcs.revision=mine\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTransportHidesUserInfo(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer ResetOutput()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	u.User = url.UserPassword("user", "hunter2")
+	u.Path = "/private"
+	l := NewLogger("client", false)
+	l.SetDebug(true)
+	c := &http.Client{Transport: Transport(nil, l)}
+	res, err := c.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	got := buf.String()
+	if !strings.Contains(got, "/private") {
+		t.Fatalf("request not logged: %q", got)
+	}
+	if strings.Contains(got, "hunter2") || strings.Contains(got, "user@") {
+		t.Errorf("user info logged: %q", got)
+	}
+}
@@ -0,0 +1,69 @@
+package log
+
+import "sync"
+
+// CodeKey is the field key used by WithCode.
+const CodeKey = "code"
+
+var codes = struct {
+	sync.RWMutex
+	registered map[string]string
+	warned     map[string]bool
+}{
+	registered: map[string]string{},
+	warned:     map[string]bool{},
+}
+
+// WithCode returns a copy of the default logger that attaches code to
+// every entry. See Logger.WithCode.
+func WithCode(code string) *Logger {
+	return defaultLogger.WithCode(code)
+}
+
+// WithCode returns a copy of the logger that attaches a stable,
+// machine readable code to every entry, so alerting rules can key on
+// the code instead of the message text.
+//
+//	logger.WithCode("DB_CONN_FAIL").Errorf("connecting: %v", err)
+//
+// If any codes have been registered with RegisterCode, using an
+// unregistered code logs a warning, once per code.
+func (l *Logger) WithCode(code string) *Logger {
+	checkCode(code)
+	return l.WithField(CodeKey, code)
+}
+
+// RegisterCode adds code to the registry of valid codes with a
+// description of what it means. Once a code is registered, WithCode
+// validates codes against the registry.
+func RegisterCode(code, description string) {
+	codes.Lock()
+	defer codes.Unlock()
+	codes.registered[code] = description
+}
+
+// CodeDescription returns the description of a registered code, and
+// whether it is registered.
+func CodeDescription(code string) (string, bool) {
+	codes.RLock()
+	defer codes.RUnlock()
+	d, ok := codes.registered[code]
+	return d, ok
+}
+
+func checkCode(code string) {
+	codes.RLock()
+	_, ok := codes.registered[code]
+	skip := ok || len(codes.registered) == 0 || codes.warned[code]
+	codes.RUnlock()
+	if skip {
+		return
+	}
+	codes.Lock()
+	warned := codes.warned[code]
+	codes.warned[code] = true
+	codes.Unlock()
+	if !warned {
+		defaultLogger.Warnf("log code %q is not registered", code)
+	}
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+	"time"
+)
+
+// Compressor wraps w in a compressing stream. Closing the stream must
+// finish it, but not close w. Streams with a Flush() error method can
+// be flushed part way, as gzip's can.
+//
+// Only gzip is built in, see Gzip. Other codecs such as zstd plug in
+// with a small adapter:
+//
+//	func(w io.Writer) io.WriteCloser {
+//		enc, _ := zstd.NewWriter(w)
+//		return enc
+//	}
+type Compressor func(w io.Writer) io.WriteCloser
+
+// Gzip returns a Compressor writing gzip at the given level, such as
+// gzip.BestSpeed. An invalid level uses gzip.DefaultCompression.
+func Gzip(level int) Compressor {
+	return func(w io.Writer) io.WriteCloser {
+		zw, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			zw = gzip.NewWriter(w)
+		}
+		return zw
+	}
+}
+
+type flusher interface {
+	Flush() error
+}
+
+// CompressWriter compresses everything written to it, as one stream,
+// into a file, connection or other writer, to cut the size of verbose
+// output such as debug captures:
+//
+//	f, _ := os.Create("debug.log.gz")
+//	cw := log.NewCompressWriter(f, log.Gzip(gzip.BestSpeed), time.Second)
+//	defer cw.Close()
+//	log.SetOutput(cw)
+//
+// Entries are buffered by the compressor and flushed to w at most
+// every flushEvery, when the next one is written, so a process that
+// dies loses at most that much output. Die flushes it before the
+// process exits. A flushEvery of 0 flushes after every entry, which
+// compresses less. The stream is only complete once Close is called.
+type CompressWriter struct {
+	mu         sync.Mutex
+	w          io.Writer
+	zw         io.WriteCloser
+	flushEvery time.Duration
+	lastFlush  time.Time
+	closed     bool
+}
+
+// NewCompressWriter returns a CompressWriter writing to w through a
+// stream from c.
+func NewCompressWriter(w io.Writer, c Compressor, flushEvery time.Duration) *CompressWriter {
+	return &CompressWriter{w: w, zw: c(w), flushEvery: flushEvery, lastFlush: time.Now()}
+}
+
+// Write compresses p, flushing the stream if it is due.
+func (c *CompressWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, io.ErrClosedPipe
+	}
+	n, err := c.zw.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if now := time.Now(); now.Sub(c.lastFlush) >= c.flushEvery {
+		c.lastFlush = now
+		if f, ok := c.zw.(flusher); ok {
+			return n, f.Flush()
+		}
+	}
+	return n, nil
+}
+
+// Flush writes out everything compressed so far.
+func (c *CompressWriter) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, ok := c.zw.(flusher); ok && !c.closed {
+		c.lastFlush = time.Now()
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close finishes the stream. It does not close the underlying writer.
+func (c *CompressWriter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.zw.Close()
+}
+
+// compress returns p compressed as a whole stream by c.
+func compress(c Compressor, p []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := c(&b)
+	if _, err := zw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
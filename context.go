@@ -0,0 +1,88 @@
+package log
+
+import (
+	"context"
+	"errors"
+)
+
+type loggerKey struct{}
+
+// NewContext returns a context carrying the logger.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+// if there is none. If ctx was marked with WithDebug, the logger has
+// debug output on.
+func FromContext(ctx context.Context) *Logger {
+	l, ok := ctx.Value(loggerKey{}).(*Logger)
+	if !ok {
+		l = defaultLogger
+	}
+	if debugContext(ctx) {
+		l = l.withDebugOn()
+	}
+	return l
+}
+
+// DieContext is Die with the logger carried by ctx, see FromContext.
+// Inside HTTPHandler, the fatal entry then identifies the request being
+// served.
+func DieContext(ctx context.Context, err error, code ...int) {
+	FromContext(ctx).die(err, code...)
+}
+
+// ErrorIfNotCanceled logs err at the error level with the default
+// logger, unless it is expected from a cancellation. See
+// Logger.ErrorIfNotCanceled.
+func ErrorIfNotCanceled(ctx context.Context, err error) {
+	defaultLogger.ErrorIfNotCanceled(ctx, err)
+}
+
+// ErrorfIfNotCanceled logs a formatted error message with the default
+// logger, unless err is expected from a cancellation. See
+// Logger.ErrorIfNotCanceled.
+func ErrorfIfNotCanceled(ctx context.Context, err error, f string, a ...interface{}) {
+	defaultLogger.ErrorfIfNotCanceled(ctx, err, f, a...)
+}
+
+// IsCanceled reports whether err is, or wraps, context.Canceled or
+// context.DeadlineExceeded, or happened after ctx was done. ctx may be
+// nil.
+func IsCanceled(ctx context.Context, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return ctx != nil && ctx.Err() != nil
+}
+
+// ErrorIfNotCanceled logs err at the error level, unless IsCanceled
+// reports it is the expected result of a cancellation, in which case
+// it is logged at the debug level. Nothing is logged if err is nil.
+//
+// This keeps shutdown paths from reporting expected cancellations as
+// errors.
+func (l *Logger) ErrorIfNotCanceled(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	if IsCanceled(ctx, err) {
+		l.debug(err)
+		return
+	}
+	l.error(err)
+}
+
+// ErrorfIfNotCanceled is like ErrorIfNotCanceled but logs a formatted
+// message, which would normally include err.
+func (l *Logger) ErrorfIfNotCanceled(ctx context.Context, err error, f string, a ...interface{}) {
+	if err == nil {
+		return
+	}
+	if IsCanceled(ctx, err) {
+		l.debugf(f, a...)
+		return
+	}
+	l.errorf(f, a...)
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// CrashReportKey is the field key for the path of the crash report
+// added to Die entries.
+const CrashReportKey = "crash_report"
+
+var crashDir struct {
+	sync.RWMutex
+	dir string
+}
+
+// SetCrashDir makes Die write a crash report to a new file in dir
+// before exiting, with the error, the build information, the entries
+// kept by KeepRecent and a dump of all goroutines, much like what
+// GOTRACEBACK gives for a panic. The Die entry gets a crash_report
+// field with the path. An empty dir turns reports off, which is the
+// default.
+//
+// The directory is created if needed. Reports are named for the time
+// and process ID, so they are never overwritten.
+func SetCrashDir(dir string) {
+	crashDir.Lock()
+	defer crashDir.Unlock()
+	crashDir.dir = dir
+}
+
+// writeCrashReport writes the report for err, returning its path, or
+// "" if reports are off or it could not be written.
+func writeCrashReport(now time.Time, err error) string {
+	crashDir.RLock()
+	dir := crashDir.dir
+	crashDir.RUnlock()
+	if dir == "" {
+		return ""
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "time: %s\npid: %d\n\nerror:\n%+v\n", now.Format(time.RFC3339Nano), os.Getpid(), err)
+	b.WriteString("\nbuild:\n")
+	b.WriteString(runtime.Version())
+	b.WriteByte('\n')
+	if info, ok := debug.ReadBuildInfo(); ok {
+		b.WriteString(info.String())
+	}
+	b.WriteString("\nrecent entries:\n")
+	for _, e := range recent.entries() {
+		jsonEncoder{}.encode(&b, &e)
+	}
+	b.WriteString("\ngoroutines:\n")
+	stack := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(stack, true)
+		if n < len(stack) || len(stack) >= 64<<20 {
+			b.Write(stack[:n])
+			break
+		}
+		stack = make([]byte, 2*len(stack))
+	}
+	name := fmt.Sprintf("crash-%s-%d.txt", now.UTC().Format("20060102T150405.000000000Z"), os.Getpid())
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		reportError(fmt.Errorf("log: writing crash report: %w", err))
+		return ""
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		reportError(fmt.Errorf("log: writing crash report: %w", err))
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,121 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// Credentials provide the secret a sink authenticates with, such as an
+// API token. Sinks ask for the secret on every request instead of
+// holding onto it, so rotated secrets are picked up without a restart.
+type Credentials interface {
+	Credential() (string, error)
+}
+
+// invalidator is implemented by Credentials that cache, so a sink can
+// drop a secret the server rejected.
+type invalidator interface {
+	Invalidate()
+}
+
+// CredentialFunc adapts a function, such as a call to a secrets
+// manager, to Credentials.
+type CredentialFunc func() (string, error)
+
+// Credential calls f.
+func (f CredentialFunc) Credential() (string, error) {
+	return f()
+}
+
+// EnvCredentials reads the secret from the environment variable name
+// on every use.
+func EnvCredentials(name string) Credentials {
+	return CredentialFunc(func() (string, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok || v == "" {
+			return "", errors.New("log: credential variable " + name + " is not set")
+		}
+		return v, nil
+	})
+}
+
+// FileCredentials reads the secret from the file at path, trimming
+// surrounding white space. The file is read again whenever its
+// modification time changes, as when a mounted secret is rotated.
+func FileCredentials(path string) Credentials {
+	return &fileCredentials{path: path}
+}
+
+type fileCredentials struct {
+	path  string
+	mu    sync.Mutex
+	mod   time.Time
+	value string
+}
+
+func (c *fileCredentials) Credential() (string, error) {
+	fi, err := os.Stat(c.path)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.value != "" && fi.ModTime().Equal(c.mod) {
+		return c.value, nil
+	}
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return "", err
+	}
+	c.value = string(bytes.TrimSpace(b))
+	c.mod = fi.ModTime()
+	return c.value, nil
+}
+
+func (c *fileCredentials) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value = ""
+}
+
+// CachedCredentials caches the secret from c for ttl, for providers
+// that are too slow or costly to ask on every request. The cache is
+// dropped early if a sink reports the secret was rejected.
+func CachedCredentials(c Credentials, ttl time.Duration) Credentials {
+	return &cachedCredentials{c: c, ttl: ttl}
+}
+
+type cachedCredentials struct {
+	c       Credentials
+	ttl     time.Duration
+	mu      sync.Mutex
+	value   string
+	expires time.Time
+}
+
+func (c *cachedCredentials) Credential() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.value != "" && time.Now().Before(c.expires) {
+		return c.value, nil
+	}
+	v, err := c.c.Credential()
+	if err != nil {
+		return "", err
+	}
+	c.value = v
+	c.expires = time.Now().Add(c.ttl)
+	return v, nil
+}
+
+func (c *cachedCredentials) Invalidate() {
+	c.mu.Lock()
+	c.value = ""
+	c.mu.Unlock()
+	if i, ok := c.c.(invalidator); ok {
+		i.Invalidate()
+	}
+}
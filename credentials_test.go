@@ -0,0 +1,166 @@
+package log
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// authServer answers 401 until it gets the token "new", and records
+// the Authorization headers it was sent.
+func authServer(t *testing.T) (url string, got func() []string) {
+	var (
+		mu   sync.Mutex
+		auth []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth = append(auth, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), auth...)
+	}
+}
+
+// rotatingFunc returns "old" the first time it is called and "new"
+// after.
+func rotatingFunc() CredentialFunc {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	return func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return "old", nil
+		}
+		return "new", nil
+	}
+}
+
+func TestHTTPWriterUnauthorizedRetry(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		creds func(t *testing.T) Credentials
+	}{
+		{"func", func(t *testing.T) Credentials { return rotatingFunc() }},
+		{"env", func(t *testing.T) Credentials {
+			t.Setenv("LOG_TEST_TOKEN", "old")
+			creds := EnvCredentials("LOG_TEST_TOKEN")
+			// Rotate the secret once the first request has read it.
+			return CredentialFunc(func() (string, error) {
+				v, err := creds.Credential()
+				os.Setenv("LOG_TEST_TOKEN", "new")
+				return v, err
+			})
+		}},
+		{"file", func(t *testing.T) Credentials {
+			path := filepath.Join(t.TempDir(), "token")
+			if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			creds := FileCredentials(path)
+			if _, err := creds.Credential(); err != nil {
+				t.Fatal(err)
+			}
+			// Replace the secret but keep the modification time, so
+			// only dropping the cached value picks it up.
+			fi, _ := os.Stat(path)
+			os.WriteFile(path, []byte("new\n"), 0o600)
+			os.Chtimes(path, fi.ModTime(), fi.ModTime())
+			return creds
+		}},
+		{"cached", func(t *testing.T) Credentials {
+			return CachedCredentials(rotatingFunc(), time.Hour)
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			url, got := authServer(t)
+			h := NewHTTPWriter(url, c.creds(t))
+			if _, err := h.Write([]byte("a")); err != nil {
+				t.Fatal(err)
+			}
+			if g := got(); len(g) != 2 || g[0] != "Bearer old" || g[1] != "Bearer new" {
+				t.Errorf("collector got %q, want the old token and then the new one", g)
+			}
+		})
+	}
+}
+
+func TestHTTPWriterUnauthorizedOnce(t *testing.T) {
+	url, got := authServer(t)
+	h := NewHTTPWriter(url, CredentialFunc(func() (string, error) { return "bad", nil }))
+	var rej *rejectedError
+	if _, err := h.Write([]byte("a")); !errors.As(err, &rej) {
+		t.Errorf("Write returned %v, want the 401 as a rejection", err)
+	}
+	if n := len(got()); n != 2 {
+		t.Errorf("collector got %d requests, want 2", n)
+	}
+}
+
+func TestHTTPWriterCredentialError(t *testing.T) {
+	url, got := authServer(t)
+	SetErrorHandler(func(error) {})
+	defer SetErrorHandler(nil)
+	h := NewHTTPWriter(url, EnvCredentials("LOG_TEST_UNSET_TOKEN"))
+
+	// Queue an entry, as after a backoff, so Write sends it first.
+	h.mu.Lock()
+	h.queue = [][]byte{[]byte("queued")}
+	h.mu.Unlock()
+
+	var rej *rejectedError
+	if _, err := h.Write([]byte("a")); !errors.As(err, &rej) {
+		t.Errorf("Write returned %v, want a rejection", err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.queue) != 0 || !h.retryAt.IsZero() {
+		t.Errorf("queue %d entries, retry at %v: a missing credential was treated as an unreachable collector", len(h.queue), h.retryAt)
+	}
+	if n := len(got()); n != 0 {
+		t.Errorf("collector got %d requests without a credential", n)
+	}
+}
+
+func TestCachedCredentials(t *testing.T) {
+	calls := 0
+	c := CachedCredentials(CredentialFunc(func() (string, error) {
+		calls++
+		if calls == 3 {
+			return "", errors.New("unavailable")
+		}
+		return "token", nil
+	}), time.Hour)
+	for i := 0; i < 3; i++ {
+		if v, err := c.Credential(); v != "token" || err != nil {
+			t.Fatalf("Credential() = %q, %v", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("source asked %d times within the ttl, want 1", calls)
+	}
+	c.(invalidator).Invalidate()
+	if c.Credential(); calls != 2 {
+		t.Errorf("source asked %d times after Invalidate, want 2", calls)
+	}
+	c.(invalidator).Invalidate()
+	if _, err := c.Credential(); err == nil {
+		t.Error("Credential hid the source's error")
+	}
+}
@@ -0,0 +1,399 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// entry is a single log message and everything needed to render it.
+type entry struct {
+	time   time.Time
+	level  Level
+	prefix string
+	// prefixCol is the precomputed text column for prefix, if set.
+	prefixCol string
+	caller    string
+	message   string
+	fields    []Field
+	// groups are the titles of the open groups, outermost first.
+	groups []string
+	// debug is set when the logger had debug enabled, which adds the
+	// level and caller columns to the text format.
+	debug bool
+	// seq is the logger's sequence, see WithSequence.
+	seq *atomic.Uint64
+}
+
+var entryPool = sync.Pool{
+	New: func() interface{} { return new(entry) },
+}
+
+func newEntry() *entry {
+	return entryPool.Get().(*entry)
+}
+
+// free returns e to the pool. e must not be used afterwards.
+func (e *entry) free() {
+	*e = entry{}
+	entryPool.Put(e)
+}
+
+// encoder renders an entry, including the trailing newline.
+type encoder interface {
+	encode(b *bytes.Buffer, e *entry)
+}
+
+// textEncoder renders the traditional column format:
+//
+//	prefix  |  message key=value
+//	LVL  |  prefix  |  caller  |  message key=value
+//
+// The level column is only shown for debug loggers, or for all
+// loggers when rendering symbols. The caller column is shown whenever
+// the entry has a caller.
+type textEncoder struct {
+	// cols are the rendered level columns, indexed by level.
+	cols    [FatalLevel + 1]string
+	symbols bool
+}
+
+func newTextEncoder(color bool, symbols map[Level]string) *textEncoder {
+	t := &textEncoder{symbols: symbols != nil}
+	for lvl := DebugLevel; lvl <= FatalLevel; lvl++ {
+		p := lvl.prefix()
+		if s, ok := symbols[lvl]; ok {
+			p = s
+		}
+		if color {
+			p = levelColors[lvl] + p + "\x1b[0m"
+		}
+		t.cols[lvl] = p + columnSep
+	}
+	return t
+}
+
+var levelColors = map[Level]string{
+	DebugLevel: "\x1b[90m",
+	InfoLevel:  "\x1b[34m",
+	WarnLevel:  "\x1b[33m",
+	ErrorLevel: "\x1b[31m",
+	FatalLevel: "\x1b[1;31m",
+}
+
+// DefaultSymbols are compact level indicators for SetSymbols.
+var DefaultSymbols = map[Level]string{
+	DebugLevel: "·",
+	InfoLevel:  "✔",
+	WarnLevel:  "⚠",
+	ErrorLevel: "✖",
+	FatalLevel: "✖",
+}
+
+func (t *textEncoder) encode(b *bytes.Buffer, e *entry) {
+	if e.level == FatalLevel {
+		b.WriteString(FatalLevel.prefix())
+		writeTextFields(b, e.fields)
+		b.WriteByte('\n')
+		b.WriteString(e.message)
+		b.WriteByte('\n')
+		return
+	}
+	if e.debug || t.symbols {
+		t.writeLevel(b, e.level)
+	}
+	if e.prefixCol != "" {
+		b.WriteString(e.prefixCol)
+	} else {
+		writePadded(b, e.prefix, prefixLimit)
+		b.WriteString(columnSep)
+	}
+	if e.caller != "" {
+		writePadded(b, e.caller, callerLimit)
+		b.WriteString(columnSep)
+	}
+	for range e.groups {
+		b.WriteString(groupIndent)
+	}
+	writeTextString(b, e.message)
+	writeTextFields(b, e.fields)
+	b.WriteByte('\n')
+}
+
+const groupIndent = "  "
+
+func (t *textEncoder) writeLevel(b *bytes.Buffer, lvl Level) {
+	if lvl < DebugLevel || lvl > FatalLevel {
+		b.WriteString(lvl.prefix())
+		b.WriteString(columnSep)
+		return
+	}
+	b.WriteString(t.cols[lvl])
+}
+
+const columnSep = "  |  "
+
+// writePadded writes s, escaped as by writeTextString, left aligned in
+// n columns, like "%-*s".
+func writePadded(b *bytes.Buffer, s string, n int) {
+	start := b.Len()
+	writeTextString(b, s)
+	for i := utf8.RuneCount(b.Bytes()[start:]); i < n; i++ {
+		b.WriteByte(' ')
+	}
+}
+
+func writeTextFields(b *bytes.Buffer, fs []Field) {
+	for _, f := range fs {
+		b.WriteByte(' ')
+		writeTextString(b, f.key)
+		b.WriteByte('=')
+		writeTextField(b, f)
+	}
+}
+
+func writeTextField(b *bytes.Buffer, f Field) {
+	switch f.kind {
+	case stringKind:
+		writeTextString(b, f.str)
+	case intKind:
+		b.Write(strconv.AppendInt(b.AvailableBuffer(), f.num, 10))
+	case uintKind:
+		b.Write(strconv.AppendUint(b.AvailableBuffer(), uint64(f.num), 10))
+	case boolKind:
+		b.Write(strconv.AppendBool(b.AvailableBuffer(), f.num != 0))
+	case floatKind:
+		b.Write(strconv.AppendFloat(b.AvailableBuffer(), math.Float64frombits(uint64(f.num)), 'g', -1, 64))
+	case durationKind:
+		b.WriteString(time.Duration(f.num).String())
+	default:
+		writeTextValue(b, f.value)
+	}
+}
+
+func writeTextValue(b *bytes.Buffer, v interface{}) {
+	switch t := v.(type) {
+	case string:
+		writeTextString(b, t)
+	case int:
+		b.Write(strconv.AppendInt(b.AvailableBuffer(), int64(t), 10))
+	case int64:
+		b.Write(strconv.AppendInt(b.AvailableBuffer(), t, 10))
+	case uint64:
+		b.Write(strconv.AppendUint(b.AvailableBuffer(), t, 10))
+	case bool:
+		b.Write(strconv.AppendBool(b.AvailableBuffer(), t))
+	default:
+		writeTextString(b, fmt.Sprint(v))
+	}
+}
+
+// writeTextString writes s with control characters other than tab
+// escaped, so an entry is always a single line.
+func writeTextString(b *bytes.Buffer, s string) {
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c != 0x7f || c == '\t' {
+			continue
+		}
+		b.WriteString(s[start:i])
+		switch c {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			b.WriteString(`\x`)
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xf])
+		}
+		start = i + 1
+	}
+	b.WriteString(s[start:])
+}
+
+// jsonEncoder renders one JSON object per line.
+type jsonEncoder struct{}
+
+func (jsonEncoder) encode(b *bytes.Buffer, e *entry) {
+	b.WriteString(`{"time":"`)
+	b.Write(e.time.AppendFormat(b.AvailableBuffer(), time.RFC3339Nano))
+	b.WriteString(`","level":"`)
+	b.WriteString(e.level.String())
+	b.WriteString(`","prefix":`)
+	writeJSONString(b, e.prefix)
+	if e.caller != "" {
+		b.WriteString(`,"caller":`)
+		writeJSONString(b, e.caller)
+	}
+	if len(e.groups) > 0 {
+		b.WriteString(`,"group":`)
+		writeJSONString(b, strings.Join(e.groups, "/"))
+	}
+	b.WriteString(`,"msg":`)
+	writeJSONString(b, e.message)
+	for _, f := range e.fields {
+		b.WriteByte(',')
+		writeJSONKey(b, f.key)
+		b.WriteByte(':')
+		writeJSONField(b, f)
+	}
+	b.WriteString("}\n")
+}
+
+// fieldKeyPrefix is put in front of field keys that would collide with
+// the keys jsonEncoder writes itself.
+const fieldKeyPrefix = "fields."
+
+// writeJSONKey writes a field key, renamed to fields.<key> if it is
+// one of the entry's own keys. Keys that already start with fields.
+// are renamed too, so a renamed key can't collide with another field.
+func writeJSONKey(b *bytes.Buffer, key string) {
+	switch key {
+	case "time", "level", "prefix", "caller", "group", "msg":
+	default:
+		if !strings.HasPrefix(key, fieldKeyPrefix) {
+			writeJSONString(b, key)
+			return
+		}
+	}
+	writeJSONString(b, fieldKeyPrefix+key)
+}
+
+func writeJSONField(b *bytes.Buffer, f Field) {
+	switch f.kind {
+	case stringKind:
+		writeJSONString(b, f.str)
+	case intKind:
+		b.Write(strconv.AppendInt(b.AvailableBuffer(), f.num, 10))
+	case uintKind:
+		b.Write(strconv.AppendUint(b.AvailableBuffer(), uint64(f.num), 10))
+	case boolKind:
+		b.Write(strconv.AppendBool(b.AvailableBuffer(), f.num != 0))
+	case floatKind:
+		v := math.Float64frombits(uint64(f.num))
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// JSON has no NaN or infinity, so they are strings.
+			b.WriteByte('"')
+			b.Write(strconv.AppendFloat(b.AvailableBuffer(), v, 'g', -1, 64))
+			b.WriteByte('"')
+			return
+		}
+		b.Write(strconv.AppendFloat(b.AvailableBuffer(), v, 'g', -1, 64))
+	case durationKind:
+		writeJSONString(b, time.Duration(f.num).String())
+	default:
+		writeJSONValue(b, f.value)
+	}
+}
+
+func writeJSONValue(b *bytes.Buffer, v interface{}) {
+	switch t := v.(type) {
+	case string:
+		writeJSONString(b, t)
+		return
+	case int:
+		b.Write(strconv.AppendInt(b.AvailableBuffer(), int64(t), 10))
+		return
+	case int64:
+		b.Write(strconv.AppendInt(b.AvailableBuffer(), t, 10))
+		return
+	case uint64:
+		b.Write(strconv.AppendUint(b.AvailableBuffer(), t, 10))
+		return
+	case bool:
+		b.Write(strconv.AppendBool(b.AvailableBuffer(), t))
+		return
+	case error:
+		writeJSONString(b, safeString(v, t.Error))
+		return
+	case json.Marshaler:
+	case fmt.Stringer:
+		writeJSONString(b, safeString(v, t.String))
+		return
+	}
+	enc, err := safeMarshal(v)
+	if err != nil {
+		writeJSONString(b, fmt.Sprint(v))
+		return
+	}
+	b.Write(enc)
+}
+
+// safeString calls f, rendering a panic the way fmt does instead of
+// taking down the caller.
+func safeString(v interface{}, f func() string) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("%%!v(PANIC=%T: %v)", v, r)
+		}
+	}()
+	return f()
+}
+
+// safeMarshal is json.Marshal, with a panicking MarshalJSON reported as
+// an error.
+func safeMarshal(v interface{}) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("json: panic in MarshalJSON: %v", r)
+		}
+	}()
+	return json.Marshal(v)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeJSONString writes s as a quoted JSON string. Invalid UTF-8 is
+// replaced with U+FFFD, as encoding/json does.
+func writeJSONString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				b.WriteString(`\u00`)
+				b.WriteByte(hexDigits[c>>4])
+				b.WriteByte(hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteString(s[start:i])
+			b.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b.WriteString(s[start:])
+	b.WriteByte('"')
+}
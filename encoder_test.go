@@ -0,0 +1,131 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// fuzzSeeds are inputs that have broken encoders: control characters,
+// invalid UTF-8, format verbs in plain messages and a large message.
+var fuzzSeeds = []struct {
+	prefix, msg, key, value string
+}{
+	{"main", "hello", "k", "v"},
+	{"a\nb", "line one\nline two", "a\nb", "x\r\ny"},
+	{"\xff\xfe", "bad \xc3\x28 utf-8", "\x80", "\xed\xa0\x80"},
+	{"fmt", "100% done %s %d %!v %%", "%v", "%s"},
+	{"big", strings.Repeat("x", 64<<10), "k", strings.Repeat("\x00", 1<<10)},
+	{"", "", "", ""},
+	{"quote", `"},{"msg":"forged"`, `"`, `\"`},
+	{"ansi", "\x1b[31mred\x1b[0m\t\x7f", "\x1b", "  "},
+}
+
+// logFuzzed logs the input through a logger with every kind of field,
+// in format f, and returns the output.
+func logFuzzed(t *testing.T, f Format, prefix, msg, key, value string) []byte {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetFormat(f)
+	defer func() {
+		SetFormat(FormatText)
+		ResetOutput()
+	}()
+	l := NewLogger(prefix, false).With(Str(key, value), Any(key+"any", value), Int(key+"n", len(value)))
+	l.Info(msg)
+	return buf.Bytes()
+}
+
+// checkOneLine fails the test unless b is a single entry ending in
+// exactly one newline.
+func checkOneLine(t *testing.T, b []byte) {
+	t.Helper()
+	if len(b) == 0 || b[len(b)-1] != '\n' {
+		t.Fatalf("entry does not end in a newline: %q", b)
+	}
+	if n := bytes.Count(b, []byte("\n")); n != 1 {
+		t.Fatalf("entry has %d newlines, want 1: %q", n, b)
+	}
+}
+
+func FuzzText(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s.prefix, s.msg, s.key, s.value)
+	}
+	f.Fuzz(func(t *testing.T, prefix, msg, key, value string) {
+		checkOneLine(t, logFuzzed(t, FormatText, prefix, msg, key, value))
+	})
+}
+
+func FuzzJSON(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s.prefix, s.msg, s.key, s.value)
+	}
+	f.Fuzz(func(t *testing.T, prefix, msg, key, value string) {
+		b := logFuzzed(t, FormatJSON, prefix, msg, key, value)
+		checkOneLine(t, b)
+		if !json.Valid(b) {
+			t.Fatalf("entry is not valid JSON: %q", b)
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			t.Fatalf("entry does not decode: %v: %q", err, b)
+		}
+	})
+}
+
+// jsonKeys returns the top level keys of the JSON object in b, in
+// order, duplicates included.
+func jsonKeys(t *testing.T, b []byte) []string {
+	t.Helper()
+	d := json.NewDecoder(bytes.NewReader(b))
+	if _, err := d.Token(); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for d.More() {
+		k, err := d.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, k.(string))
+		var v json.RawMessage
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keys
+}
+
+func TestJSONFieldCollisions(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetFormat(FormatJSON)
+	defer func() {
+		SetFormat(FormatText)
+		ResetOutput()
+	}()
+	NewLogger("api", false).With(
+		Str("msg", "field"), Int("level", 3), Str("time", "t"),
+		Str("prefix", "p"), Str("caller", "c"), Str("group", "g"),
+		Str("fields.msg", "nested"), Str("user", "u"),
+	).Info("message")
+
+	want := []string{
+		"time", "level", "prefix", "msg",
+		"fields.msg", "fields.level", "fields.time", "fields.prefix",
+		"fields.caller", "fields.group", "fields.fields.msg", "user",
+	}
+	got := jsonKeys(t, buf.Bytes())
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("keys = %q, want %q", got, want)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["msg"] != "message" || m["level"] != "info" || m["fields.msg"] != "field" {
+		t.Errorf("entry = %v, want msg and level from the entry and the field renamed", m)
+	}
+}
@@ -0,0 +1,266 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// EnvVars are the variables checked, in order, by the default
+// environment detector.
+var EnvVars = []string{"DEPLOY_ENV", "APP_ENV", "GO_ENV"}
+
+var envDetector = struct {
+	sync.RWMutex
+	fn func() string
+}{fn: detectEnv}
+
+// init applies the environment. Problems with it are reported through
+// the output, which package variable initialization, done before any
+// init function, has already pointed at the test buffer under go test.
+func init() {
+	loadEnv()
+	reportEnv()
+}
+
+// Environment returns the name of the deployment environment, such
+// as "dev" or "production", as reported by the environment detector.
+func Environment() string {
+	envDetector.RLock()
+	fn := envDetector.fn
+	envDetector.RUnlock()
+	return fn()
+}
+
+// SetEnvDetector replaces the function used to find the deployment
+// environment and applies the settings for the environment it returns.
+// Invalid LOG_* variables are only reported, or stop the process with
+// LOG_STRICT, at startup, not again when this is called.
+// A nil fn restores the default, which returns the first non empty
+// variable in EnvVars.
+func SetEnvDetector(fn func() string) {
+	if fn == nil {
+		fn = detectEnv
+	}
+	envDetector.Lock()
+	envDetector.fn = fn
+	envDetector.Unlock()
+	loadEnv()
+}
+
+func detectEnv() string {
+	for _, k := range EnvVars {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// RefreshEnv re-reads the environment and updates the debug setting
+// and minimum level to match, discarding any changes made with
+// SetDebug or SetLevel. Other settings are left alone.
+func RefreshEnv() {
+	storeLevels(envLevels())
+}
+
+// RefreshEnv re-reads the environment. The environment settings are
+// shared by all loggers, so this is the same as the package level
+// RefreshEnv.
+func (l *Logger) RefreshEnv() {
+	RefreshEnv()
+}
+
+// RefreshEnvOnSignal calls RefreshEnv whenever the process receives
+// one of sigs, SIGHUP if none are given, until stop is called. Where
+// the platform has no SIGHUP, such as js/wasm, it does nothing unless
+// sigs are given.
+func RefreshEnvOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = refreshSignals
+	}
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sigs...)
+	go func() {
+		for {
+			select {
+			case <-c:
+				RefreshEnv()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
+
+// CheckEnv returns an error describing each LOG_* variable with an
+// invalid value, or nil if they are all valid. Invalid values are
+// ignored, with a warning logged when the environment is loaded, unless
+// LOG_STRICT is set, in which case the process exits at startup.
+//
+// To fail startup some other way, check the environment first thing
+// in main:
+//
+//	if err := log.CheckEnv(); err != nil {
+//		log.Die(err)
+//	}
+func CheckEnv() error {
+	var errs []error
+	env := envErrors()
+	for _, k := range []string{"LOG_LEVEL", "LOG_FORMAT", "LOG_PROFILE", "LOG_FIELDS"} {
+		if err := env[k]; err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", k, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// envErrors returns the errors in the LOG_* variables, keyed by name.
+func envErrors() map[string]error {
+	errs := map[string]error{}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if _, err := ParseLevel(v); err != nil {
+			errs["LOG_LEVEL"] = err
+		}
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		if _, err := ParseFormat(v); err != nil {
+			errs["LOG_FORMAT"] = err
+		}
+	}
+	if v := os.Getenv("LOG_PROFILE"); v != "" && v != "none" && v != "auto" {
+		if _, ok := ProfileFor(v); !ok {
+			errs["LOG_PROFILE"] = fmt.Errorf("unknown log profile %q", v)
+		}
+	}
+	if _, err := parseEnvFields(os.Getenv("LOG_FIELDS")); err != nil {
+		errs["LOG_FIELDS"] = err
+	}
+	return errs
+}
+
+// envFields holds the []Field from LOG_FIELDS.
+var envFields atomic.Value
+
+// parseEnvFields parses a LOG_FIELDS value, a comma separated list of
+// key=value pairs. Pairs that fail to parse are skipped and reported
+// in the error.
+func parseEnvFields(v string) ([]Field, error) {
+	var fs []Field
+	var bad []string
+	for _, kv := range strings.Split(v, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(kv, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			bad = append(bad, kv)
+			continue
+		}
+		fs = append(fs, Str(k, strings.TrimSpace(val)))
+	}
+	if len(bad) > 0 {
+		return fs, fmt.Errorf("invalid fields %q, want key=value", bad)
+	}
+	return fs, nil
+}
+
+func appendEnvFields(fs []Field) []Field {
+	efs, _ := envFields.Load().([]Field)
+	return append(fs, efs...)
+}
+
+// loadEnv applies the profile for the environment, then the LOG_*
+// variables on top of it.
+func loadEnv() {
+	if p, ok := envProfile(); ok {
+		SetProfile(p)
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		if f, err := ParseFormat(v); err == nil {
+			SetFormat(f)
+		}
+	}
+	fs, _ := parseEnvFields(os.Getenv("LOG_FIELDS"))
+	envFields.Store(fs)
+	RefreshEnv()
+}
+
+// reportEnv logs a warning for invalid LOG_* variables, or exits if
+// LOG_STRICT is set.
+func reportEnv() {
+	errs := envErrors()
+	if len(errs) == 0 {
+		return
+	}
+	if os.Getenv("LOG_STRICT") != "" {
+		fmt.Fprintf(os.Stderr, "log: invalid environment: %v\n", CheckEnv())
+		os.Exit(2)
+	}
+	fs := make(Fields, len(errs))
+	for k, err := range errs {
+		fs[k] = err.Error()
+	}
+	defaultLogger.WithFields(fs).Warn("log: ignoring invalid environment variables, using defaults")
+}
+
+// envProfile returns the profile for the Environment, or if there is
+// none, the one named by LOG_PROFILE. Without either, the profile is
+// picked by whether stdout is a terminal: Development if it is and
+// Production if not. LOG_PROFILE=none keeps the package defaults, as
+// does running under go test.
+func envProfile() (Profile, bool) {
+	if p, ok := ProfileFor(Environment()); ok {
+		return p, true
+	}
+	switch v := os.Getenv("LOG_PROFILE"); v {
+	case "none":
+		return Profile{}, false
+	case "", "auto":
+		if testBinary {
+			return Profile{}, false
+		}
+		if terminalFile(os.Stdout) != nil {
+			return Development, true
+		}
+		return Production, true
+	default:
+		return ProfileFor(v)
+	}
+}
+
+// envLevels returns the debug setting and minimum level from the
+// environment's profile and the LOG_* variables.
+func envLevels() (bool, Level) {
+	debug, lvl := false, DebugLevel
+	if p, ok := envProfile(); ok {
+		debug, lvl = p.Debug, p.Level
+	}
+	if checkDebugEnabled() {
+		debug = true
+		if lvl > DebugLevel {
+			lvl = DebugLevel
+		}
+	}
+	if l, ok := envLevel(); ok {
+		lvl = l
+	}
+	return debug, lvl
+}
@@ -0,0 +1,25 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetEnvDetectorDoesNotReportEnv(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	t.Cleanup(func() { SetEnvDetector(nil) })
+	t.Setenv("LOG_STRICT", "1")
+	t.Setenv("LOG_LEVEL", "loud")
+
+	// With LOG_STRICT, reporting the invalid LOG_LEVEL would exit the
+	// test binary here.
+	SetEnvDetector(func() string { return "staging" })
+	if got := Environment(); got != "staging" {
+		t.Errorf("Environment() = %q, want staging", got)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("SetEnvDetector logged %q", buf.String())
+	}
+}
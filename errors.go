@@ -0,0 +1,95 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// SinkError is passed to the error handler when writing to a log
+// destination fails.
+type SinkError struct {
+	Sink string
+	Err  error
+}
+
+func (e *SinkError) Error() string {
+	return fmt.Sprintf("log: writing to %s: %v", e.Sink, e.Err)
+}
+
+func (e *SinkError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errorHandler = struct {
+		sync.RWMutex
+		fn func(error)
+	}{fn: defaultErrorHandler}
+	noStderrFallback int32
+	// reporting holds the IDs of the goroutines running the error
+	// handler, to guard against a handler that logs through a failing
+	// sink.
+	reporting sync.Map
+)
+
+// SetErrorHandler sets the function called when the package fails
+// internally, such as when a sink write fails. The default writes the
+// error to stderr. A nil fn restores the default.
+//
+// fn must not block for long, and must be safe for concurrent use, as
+// errors from different goroutines are reported at the same time.
+// Errors from entries logged by fn itself are not reported again.
+func SetErrorHandler(fn func(error)) {
+	if fn == nil {
+		fn = defaultErrorHandler
+	}
+	errorHandler.Lock()
+	defer errorHandler.Unlock()
+	errorHandler.fn = fn
+}
+
+// SetStderrFallback sets whether entries that fail to be written are
+// written to stderr instead, so they are not lost. It is on by
+// default.
+func SetStderrFallback(enabled bool) {
+	var v int32
+	if !enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&noStderrFallback, v)
+}
+
+func stderrFallback() bool {
+	return atomic.LoadInt32(&noStderrFallback) == 0
+}
+
+// reportError calls the error handler with err, unless the calling
+// goroutine is already running it.
+func reportError(err error) {
+	id := goroutineID()
+	if _, ok := reporting.LoadOrStore(id, struct{}{}); ok {
+		return
+	}
+	defer reporting.Delete(id)
+	errorHandler.RLock()
+	fn := errorHandler.fn
+	errorHandler.RUnlock()
+	fn(err)
+}
+
+// reportErrorAsync is reportError from a new goroutine, for callers
+// holding the output lock, which a handler that logs would wait on.
+// Errors raised while the calling goroutine runs the handler are still
+// dropped.
+func reportErrorAsync(err error) {
+	if _, ok := reporting.Load(goroutineID()); ok {
+		return
+	}
+	go reportError(err)
+}
+
+func defaultErrorHandler(err error) {
+	fmt.Fprintln(os.Stderr, err)
+}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReportErrorConcurrent(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		got     []string
+		entered = make(chan struct{})
+		release = make(chan struct{})
+	)
+	SetErrorHandler(func(err error) {
+		mu.Lock()
+		got = append(got, err.Error())
+		first := len(got) == 1
+		mu.Unlock()
+		if first {
+			close(entered)
+			<-release
+		}
+	})
+	defer SetErrorHandler(nil)
+
+	done := make(chan struct{})
+	go func() {
+		reportError(errors.New("one"))
+		close(done)
+	}()
+	<-entered
+	// Reported from another goroutine while the handler runs.
+	reportError(errors.New("two"))
+	close(release)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 {
+		t.Errorf("handler got %q, want both errors", got)
+	}
+}
+
+func TestReportErrorFromHandler(t *testing.T) {
+	var calls atomic.Int32
+	SetOutput(failingWriter{})
+	defer ResetOutput()
+	SetStderrFallback(false)
+	defer SetStderrFallback(true)
+	l := NewLogger("handler", false)
+	SetErrorHandler(func(err error) {
+		calls.Add(1)
+		// Both end up reporting errors from this goroutine.
+		l.Info("logging the error")
+		reportErrorAsync(errors.New("async"))
+	})
+	defer SetErrorHandler(nil)
+
+	l.Info("fails")
+	time.Sleep(20 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+}
@@ -0,0 +1,49 @@
+package log_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	log "github.com/dangersalad/go-log"
+)
+
+// csvEncoder renders entries as level,prefix,message.
+type csvEncoder struct{}
+
+func (csvEncoder) Encode(b *bytes.Buffer, e *log.Entry) {
+	fmt.Fprintf(b, "%s,%s,%q\n", e.Level, e.Prefix, e.Message)
+}
+
+func ExampleSetEncoder() {
+	log.SetOutput(os.Stdout)
+	log.SetEncoder(csvEncoder{})
+	defer log.ResetOutput()
+	defer log.SetFormat(log.FormatText)
+
+	log.NewLogger("api", false).Info("listening")
+	// Output:
+	// info,api,"listening"
+}
+
+// countSink counts the entries at each level.
+type countSink map[log.Level]int
+
+func (c countSink) WriteEntry(e *log.Entry, encoded []byte) error {
+	c[e.Level]++
+	return nil
+}
+
+func ExampleAddSink() {
+	counts := countSink{}
+	remove := log.AddSink(counts, nil)
+	l := log.NewLogger("job", false)
+	l.Warn("retrying")
+	l.Warn("retrying")
+	l.Error("gave up")
+	remove()
+
+	fmt.Println(counts[log.WarnLevel], counts[log.ErrorLevel])
+	// Output:
+	// 2 1
+}
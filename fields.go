@@ -0,0 +1,335 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// WorkerKey is the field key used by WithWorker.
+const WorkerKey = "worker"
+
+// GoroutineKey is the field key used for the goroutine ID when
+// enabled with SetGoroutineID.
+const GoroutineKey = "goid"
+
+// Fields is a set of key/value pairs to attach to log entries.
+type Fields map[string]interface{}
+
+// Field is a key/value pair attached to an entry. The typed
+// constructors, such as Str and Int, hold their value without boxing it
+// in an interface, so logging them does not allocate.
+type Field struct {
+	key  string
+	kind fieldKind
+	// num holds ints, bools, durations and float bits, and str
+	// strings. value holds everything else.
+	num   int64
+	str   string
+	value interface{}
+}
+
+type fieldKind uint8
+
+const (
+	anyKind fieldKind = iota
+	stringKind
+	intKind
+	uintKind
+	boolKind
+	floatKind
+	durationKind
+	// lazyKind fields hold a func() interface{} in value, replaced by
+	// its result in emit.
+	lazyKind
+	// providerKind fields hold a func() Fields in value, replaced by
+	// the fields it returns in emit.
+	providerKind
+)
+
+// ErrorKey is the field key used by Err.
+const ErrorKey = "error"
+
+// Any returns a field with any value.
+func Any(key string, value interface{}) Field {
+	return Field{key: key, value: value}
+}
+
+// Str returns a string field.
+func Str(key, value string) Field {
+	return Field{key: key, kind: stringKind, str: value}
+}
+
+// Int returns an int field.
+func Int(key string, value int) Field {
+	return Field{key: key, kind: intKind, num: int64(value)}
+}
+
+// Int64 returns an int64 field.
+func Int64(key string, value int64) Field {
+	return Field{key: key, kind: intKind, num: value}
+}
+
+// Uint64 returns a uint64 field.
+func Uint64(key string, value uint64) Field {
+	return Field{key: key, kind: uintKind, num: int64(value)}
+}
+
+// Bool returns a bool field.
+func Bool(key string, value bool) Field {
+	f := Field{key: key, kind: boolKind}
+	if value {
+		f.num = 1
+	}
+	return f
+}
+
+// Float64 returns a float64 field.
+func Float64(key string, value float64) Field {
+	return Field{key: key, kind: floatKind, num: int64(math.Float64bits(value))}
+}
+
+// Dur returns a duration field, rendered like time.Duration.String.
+func Dur(key string, value time.Duration) Field {
+	return Field{key: key, kind: durationKind, num: int64(value)}
+}
+
+// Err returns an error field with ErrorKey as the key.
+func Err(err error) Field {
+	return Field{key: ErrorKey, value: err}
+}
+
+// Key returns the key of the field.
+func (f Field) Key() string {
+	return f.key
+}
+
+// Value returns the value of the field.
+func (f Field) Value() interface{} {
+	switch f.kind {
+	case stringKind:
+		return f.str
+	case intKind:
+		return f.num
+	case uintKind:
+		return uint64(f.num)
+	case boolKind:
+		return f.num != 0
+	case floatKind:
+		return math.Float64frombits(uint64(f.num))
+	case durationKind:
+		return time.Duration(f.num)
+	case lazyKind:
+		return f.value.(func() interface{})()
+	}
+	return f.value
+}
+
+// stringValue returns the value of a string field, or the value
+// formatted with fmt.Sprint.
+func (f Field) stringValue() string {
+	switch f.kind {
+	case stringKind:
+		return f.str
+	case anyKind:
+		if s, ok := f.value.(string); ok {
+			return s
+		}
+	}
+	return fmt.Sprint(f.Value())
+}
+
+var goroutineIDEnabled int32
+
+// SetGoroutineID enables or disables adding the ID of the logging
+// goroutine to every entry.
+//
+// Looking up the goroutine ID is not free, so this is meant for
+// tracking down interleaved output, not for always-on use. Prefer
+// WithWorker for labeling worker pools.
+func SetGoroutineID(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&goroutineIDEnabled, v)
+}
+
+// WithField returns a copy of the default logger with the field added
+func WithField(key string, value interface{}) *Logger {
+	return defaultLogger.WithField(key, value)
+}
+
+// WithFields returns a copy of the default logger with the fields
+// added
+func WithFields(f Fields) *Logger {
+	return defaultLogger.WithFields(f)
+}
+
+// WithWorker returns a copy of the default logger labeled with the
+// worker ID
+func WithWorker(id interface{}) *Logger {
+	return defaultLogger.WithWorker(id)
+}
+
+// WithField returns a copy of the logger with the field added to
+// every entry it logs.
+func (l *Logger) WithField(key string, value interface{}) *Logger {
+	return l.with(Any(key, value))
+}
+
+// WithFields returns a copy of the logger with the fields added to
+// every entry it logs. Fields are rendered in key order.
+func (l *Logger) WithFields(f Fields) *Logger {
+	return l.with(sortedFields(f)...)
+}
+
+// WithProvider returns a copy of the default logger with the provider
+// added. See Logger.WithProvider.
+func WithProvider(fn func() Fields) *Logger {
+	return defaultLogger.WithProvider(fn)
+}
+
+// WithProvider returns a copy of the logger that calls fn for every
+// entry it logs and adds the fields it returns, in key order, for
+// values that change over time:
+//
+//	logger = logger.WithProvider(func() log.Fields {
+//		return log.Fields{"inflight": atomic.LoadInt64(&inflight)}
+//	})
+//
+// fn is only called for entries that are logged. It runs on the
+// logging goroutine and must be safe for concurrent use.
+func (l *Logger) WithProvider(fn func() Fields) *Logger {
+	return l.with(Field{kind: providerKind, value: fn})
+}
+
+func sortedFields(f Fields) []Field {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fs := make([]Field, len(keys))
+	for i, k := range keys {
+		fs[i] = Any(k, f[k])
+	}
+	return fs
+}
+
+// WithWorker returns a copy of the logger labeled with the worker
+// ID, so output from goroutines in a pool can be told apart.
+//
+//	for i := 0; i < n; i++ {
+//		go work(logger.WithWorker(i))
+//	}
+func (l *Logger) WithWorker(id interface{}) *Logger {
+	return l.WithField(WorkerKey, id)
+}
+
+// With returns a copy of the default logger with the fields added. See
+// Logger.With.
+func With(fs ...Field) *Logger {
+	return defaultLogger.With(fs...)
+}
+
+// With returns a copy of the logger with the fields added to every
+// entry it logs, in the order given.
+//
+//	logger.With(log.Str("user", id), log.Int("attempt", n))
+//
+// If a key is added more than once, the last value wins and is
+// rendered where the key first appeared. Fields are added, from first
+// to last, by SetGoroutineID, SetBuildInfo and LOG_FIELDS, by the
+// logger and the loggers it was derived from, oldest first, by the
+// logging call, by SetSequence or WithSequence, by SetEntryIDs, and by
+// hooks, so the call's fields override the logger's, the seq and id
+// fields override fields of the same name, and hooks override all of
+// them.
+//
+// Fields never replace the entry's own values. In JSON, a field named
+// time, level, prefix, caller, group or msg is written as fields.msg
+// and so on, and a key that already starts with "fields." gets a
+// second one, so every key in an object is unique.
+func (l *Logger) With(fs ...Field) *Logger {
+	return l.with(fs...)
+}
+
+func (l *Logger) with(fs ...Field) *Logger {
+	c := l.derive()
+	c.fields = make([]Field, 0, len(l.fields)+len(fs))
+	c.fields = append(c.fields, l.fields...)
+	c.fields = append(c.fields, fs...)
+	return c
+}
+
+// dedupFields returns fs with only the last value for each key, each at
+// the position of its key's first field. fs is returned as is if it has
+// no repeated keys.
+func dedupFields(fs []Field) []Field {
+	if len(fs) < 2 || !hasDupKeys(fs) {
+		return fs
+	}
+	out := make([]Field, 0, len(fs))
+	at := make(map[string]int, len(fs))
+	for _, f := range fs {
+		if i, ok := at[f.key]; ok {
+			out[i] = f
+			continue
+		}
+		at[f.key] = len(out)
+		out = append(out, f)
+	}
+	return out
+}
+
+func hasDupKeys(fs []Field) bool {
+	if len(fs) <= 16 {
+		for i := 1; i < len(fs); i++ {
+			for j := 0; j < i; j++ {
+				if fs[i].key == fs[j].key {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	seen := make(map[string]struct{}, len(fs))
+	for _, f := range fs {
+		if _, ok := seen[f.key]; ok {
+			return true
+		}
+		seen[f.key] = struct{}{}
+	}
+	return false
+}
+
+func (l *Logger) entryFields() []Field {
+	var fs []Field
+	if atomic.LoadInt32(&goroutineIDEnabled) != 0 {
+		fs = append(fs, Any(GoroutineKey, goroutineID()))
+	}
+	fs = appendEnvFields(fs)
+	if fs == nil {
+		return l.fields
+	}
+	return append(fs, l.fields...)
+}
+
+// goroutineID parses the ID out of the "goroutine N [...]" header of
+// the current stack.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
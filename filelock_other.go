@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd)
+
+package log
+
+import "os"
+
+// lockFile is a no-op where flock is not available; entries still
+// rely on O_APPEND.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) {}
@@ -0,0 +1,262 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Rotation is when a RotatingFile is rotated and how many old files
+// are kept.
+type Rotation struct {
+	// MaxBytes is the size a file is rotated at. 0 or less means the
+	// file is never rotated.
+	MaxBytes int64
+	// MaxBackups is the number of rotated files kept, named with .1,
+	// .2 and so on added to the path, newest first. With 0, the file
+	// is truncated when it is rotated.
+	MaxBackups int
+}
+
+// RotatingFile is a writer that appends to a file and rotates it once
+// it would grow past a size. Each Write goes whole into one file, so
+// entries are not split across files.
+//
+// Only one process should write to a RotatingFile's path, as each
+// process tracks the size and rotates on its own; SetFileLocking does
+// not apply. Processes sharing a file should use SetOutputFile.
+type RotatingFile struct {
+	mu     sync.Mutex
+	path   string
+	policy Rotation
+	// f is nil if the file could not be opened again after a
+	// rotation, in which case the next Write tries again.
+	f      *os.File
+	size   int64
+	closed bool
+}
+
+// OpenRotatingFile opens, or creates, the file at path for appending,
+// rotating it with policy.
+func OpenRotatingFile(path string, policy Rotation) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, policy: policy}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, fi.Size()
+	return nil
+}
+
+// Write appends p to the file, rotating it first if p would take it
+// past the policy's size. If the rotation fails, such as when a backup
+// can't be renamed, p is appended to the current file, the error is
+// reported to the error handler, see SetErrorHandler, and the rotation
+// is tried again on the next Write.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if max := r.policy.MaxBytes; max > 0 && r.size > 0 && r.size+int64(len(p)) > max {
+		if err := r.rotate(); err != nil {
+			if r.f == nil {
+				return 0, err
+			}
+			// Reported from another goroutine, as Write is called
+			// with the output locked and the handler may log.
+			reportErrorAsync(fmt.Errorf("log: rotating %s: %w", r.path, err))
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups along, dropping the oldest, and starts a
+// new file. If that fails, the current file is opened again.
+func (r *RotatingFile) rotate() error {
+	err := r.f.Close()
+	r.f = nil
+	if err == nil {
+		err = r.shift()
+	}
+	if err != nil {
+		if oerr := r.open(); oerr != nil {
+			return oerr
+		}
+		return err
+	}
+	return r.open()
+}
+
+// shift moves the closed file to the first backup, or removes it if
+// there are no backups.
+func (r *RotatingFile) shift() error {
+	if n := r.policy.MaxBackups; n > 0 {
+		os.Remove(r.backup(n))
+		for i := n - 1; i > 0; i-- {
+			os.Rename(r.backup(i), r.backup(i+1))
+		}
+		if err := os.Rename(r.path, r.backup(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *RotatingFile) backup(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+// Close closes the file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// SplitBy is what SplitFiles splits entries by.
+type SplitBy int
+
+const (
+	// SplitByLevel splits entries by the name of their level, such
+	// as "error" or "debug".
+	SplitByLevel SplitBy = iota
+	// SplitByPrefix splits entries by their logger's prefix.
+	SplitByPrefix
+)
+
+// SplitFiles is a writer that writes entries to a file per level or
+// per logger prefix in a directory, each with its own rotation, for
+// hosts where entries are triaged by file:
+//
+//	s := log.NewSplitFiles("/var/log/app", log.SplitByLevel, log.Rotation{MaxBytes: 100 << 20, MaxBackups: 3})
+//	s.File("error", "errors.log", log.Rotation{MaxBytes: 10 << 20, MaxBackups: 10})
+//	log.SetOutput(s)
+//
+// Entries with a key that has no file set with File go to the key with
+// ".log" added, with the default rotation. Files are opened on first
+// use. Anything written to SplitFiles directly, and entries from
+// loggers without a prefix, go to the file for the key "", "other.log"
+// unless set.
+type SplitFiles struct {
+	dir   string
+	by    SplitBy
+	def   Rotation
+	mu    sync.Mutex
+	names map[string]string
+	rules map[string]Rotation
+	files map[string]*RotatingFile
+}
+
+// NewSplitFiles returns a SplitFiles writing to files in dir, rotated
+// with def unless set otherwise with File.
+func NewSplitFiles(dir string, by SplitBy, def Rotation) *SplitFiles {
+	return &SplitFiles{
+		dir:   dir,
+		by:    by,
+		def:   def,
+		names: map[string]string{},
+		rules: map[string]Rotation{},
+		files: map[string]*RotatingFile{},
+	}
+}
+
+// File sets the file name, in the directory, and the rotation for
+// entries with the key, a level name or a prefix. It applies to files
+// not opened yet.
+func (s *SplitFiles) File(key, name string, policy Rotation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.names[key] = name
+	s.rules[key] = policy
+}
+
+// Write writes p to the file for the key "".
+func (s *SplitFiles) Write(p []byte) (int, error) {
+	f, err := s.file("")
+	if err != nil {
+		return 0, err
+	}
+	return f.Write(p)
+}
+
+func (s *SplitFiles) writeEntry(e *entry, p []byte) error {
+	key := e.prefix
+	if s.by == SplitByLevel {
+		key = e.level.String()
+	}
+	f, err := s.file(key)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(p)
+	return err
+}
+
+func (s *SplitFiles) file(key string) (*RotatingFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.files[key]; ok {
+		return f, nil
+	}
+	name, ok := s.names[key]
+	if !ok {
+		name = key + ".log"
+		if key == "" {
+			name = "other.log"
+		}
+	}
+	policy, ok := s.rules[key]
+	if !ok {
+		policy = s.def
+	}
+	f, err := OpenRotatingFile(filepath.Join(s.dir, filepath.Base(name)), policy)
+	if err != nil {
+		return nil, err
+	}
+	s.files[key] = f
+	return f, nil
+}
+
+// Close closes all the open files.
+func (s *SplitFiles) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var first error
+	for key, f := range s.files {
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+		delete(s.files, key)
+	}
+	return first
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFileSurvivesFailedRotation(t *testing.T) {
+	SetErrorHandler(func(error) {})
+	defer SetErrorHandler(nil)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	r, err := OpenRotatingFile(path, Rotation{MaxBytes: 10, MaxBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// A non-empty directory where the backup goes makes the rename
+	// fail.
+	if err := os.MkdirAll(filepath.Join(path+".1", "busy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := r.Write([]byte(s)); err != nil {
+			t.Fatalf("Write(%q) after a failed rotation: %v", s, err)
+		}
+	}
+	if b, _ := os.ReadFile(path); string(b) != "first\nsecond\nthird\n" {
+		t.Errorf("file has %q, want all entries", b)
+	}
+
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("fourth\n")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "fourth\n" {
+		t.Errorf("file after rotating has %q, want the new entry", b)
+	}
+	if b, _ := os.ReadFile(path + ".1"); string(b) != "first\nsecond\nthird\n" {
+		t.Errorf("backup has %q, want the old entries", b)
+	}
+
+	r.Close()
+	if _, err := r.Write([]byte("late\n")); err != os.ErrClosed {
+		t.Errorf("Write after Close = %v, want os.ErrClosed", err)
+	}
+}
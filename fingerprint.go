@@ -0,0 +1,44 @@
+package log
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// FingerprintKey is the field key used by Fingerprint.
+const FingerprintKey = "fingerprint"
+
+// fingerprintFrames is the number of calling frames in a fingerprint.
+const fingerprintFrames = 5
+
+// Fingerprint returns a field identifying where and what kind of
+// error err is, so downstream tools can group repeats of the same
+// error:
+//
+//	logger.With(log.Err(err), log.Fingerprint(err)).Error("sync failed")
+//
+// The fingerprint is a hash of the types of the errors in err's chain
+// and the functions of the top calling frames. Messages and line
+// numbers are left out, so the fingerprint stays the same across
+// deploys and for errors that include IDs in their messages.
+func Fingerprint(err error) Field {
+	h := sha1.New()
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		fmt.Fprintf(h, "%T\n", e)
+	}
+	var pcs [fingerprintFrames]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		h.Write([]byte(f.Function))
+		h.Write([]byte{'\n'})
+		if !more {
+			break
+		}
+	}
+	return Str(FingerprintKey, hex.EncodeToString(h.Sum(nil)[:8]))
+}
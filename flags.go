@@ -0,0 +1,149 @@
+package log
+
+import (
+	"flag"
+	"strconv"
+)
+
+// RegisterFlags adds the logging flags to fs, wired into the package
+// configuration:
+//
+//	-log-level   minimum level: debug, info, warn or error
+//	-log-format  output format: text, line, json or syslog
+//	-log-file    append output to this file instead of stdout
+//	-verbose     enable debug output
+//
+// If fs is nil, flag.CommandLine is used.
+func RegisterFlags(fs *flag.FlagSet) {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	fs.Var(LevelValue(), "log-level", "minimum log `level`: debug, info, warn or error")
+	fs.Var(FormatValue(), "log-format", "log output `format`: text, line, json or syslog")
+	fs.Var(FileValue(), "log-file", "append log output to `path` instead of stdout")
+	fs.Var(VerboseValue(), "verbose", "enable debug logging")
+}
+
+// Value is a package setting that can be bound to a flag. It
+// satisfies both flag.Value and the pflag.Value interface used by
+// cobra, so frameworks can bind the settings without this package
+// importing them:
+//
+//	cmd.PersistentFlags().Var(log.LevelValue(), "log-level", "minimum log level")
+//
+// Setting a Value changes the package configuration immediately.
+type Value interface {
+	String() string
+	Set(string) error
+	Type() string
+}
+
+// LevelValue returns a Value that sets the minimum level. Setting
+// "debug" also enables debug output.
+func LevelValue() Value {
+	return levelFlag{}
+}
+
+// FormatValue returns a Value that sets the output format.
+func FormatValue() Value {
+	return formatFlag{}
+}
+
+// FileValue returns a Value that sets the output to a file.
+func FileValue() Value {
+	return &fileFlag{}
+}
+
+// VerboseValue returns a boolean Value that enables debug output.
+// It has an IsBoolFlag method for the flag package; with pflag, set
+// NoOptDefVal to "true" on the flag.
+func VerboseValue() Value {
+	return verboseFlag{}
+}
+
+type levelFlag struct{}
+
+func (levelFlag) String() string {
+	return GetLevel().String()
+}
+
+func (levelFlag) Type() string {
+	return "level"
+}
+
+func (levelFlag) Set(s string) error {
+	lvl, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	SetLevel(lvl)
+	if lvl == DebugLevel {
+		SetDebug(true)
+	}
+	return nil
+}
+
+type formatFlag struct{}
+
+func (formatFlag) String() string {
+	return string(GetFormat())
+}
+
+func (formatFlag) Type() string {
+	return "format"
+}
+
+func (formatFlag) Set(s string) error {
+	f, err := ParseFormat(s)
+	if err != nil {
+		return err
+	}
+	SetFormat(f)
+	return nil
+}
+
+type fileFlag struct {
+	path string
+}
+
+func (f *fileFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.path
+}
+
+func (*fileFlag) Type() string {
+	return "string"
+}
+
+func (f *fileFlag) Set(s string) error {
+	if err := SetOutputFile(s); err != nil {
+		return err
+	}
+	f.path = s
+	return nil
+}
+
+type verboseFlag struct{}
+
+func (verboseFlag) String() string {
+	return strconv.FormatBool(debugSetting())
+}
+
+func (verboseFlag) Type() string {
+	return "bool"
+}
+
+func (verboseFlag) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	SetDebug(v)
+	return nil
+}
+
+func (verboseFlag) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,160 @@
+package log
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		args     []string
+		level    Level
+		debug    bool
+		contains []string
+		missing  []string
+	}{
+		{
+			name:     "verbose",
+			args:     []string{"-verbose"},
+			level:    DebugLevel,
+			debug:    true,
+			contains: []string{"debug line", "info line"},
+		},
+		{
+			name:     "verbose under warn",
+			args:     []string{"-log-level=warn", "-verbose"},
+			level:    DebugLevel,
+			debug:    true,
+			contains: []string{"debug line", "info line", "warn line"},
+		},
+		{
+			name:     "verbose=false",
+			args:     []string{"-verbose=false"},
+			level:    InfoLevel,
+			missing:  []string{"debug line"},
+			contains: []string{"info line"},
+		},
+		{
+			name:     "level debug",
+			args:     []string{"-log-level", "debug"},
+			level:    DebugLevel,
+			debug:    true,
+			contains: []string{"debug line", "info line"},
+		},
+		{
+			name:     "level warn",
+			args:     []string{"-log-level=warn"},
+			level:    WarnLevel,
+			contains: []string{"warn line"},
+			missing:  []string{"debug line", "info line"},
+		},
+		{
+			name:    "level error",
+			args:    []string{"-log-level=ERROR"},
+			level:   ErrorLevel,
+			missing: []string{"debug line", "info line", "warn line"},
+		},
+		{
+			name:     "json",
+			args:     []string{"-log-format=json", "-verbose"},
+			level:    DebugLevel,
+			debug:    true,
+			contains: []string{`"level":"debug"`, `"msg":"info line"`},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			keepSettings(t)
+			SetProfile(Production)
+			SetFormat(FormatText)
+			var buf bytes.Buffer
+			SetOutput(&buf)
+			t.Cleanup(ResetOutput)
+
+			fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+			RegisterFlags(fs)
+			if err := fs.Parse(c.args); err != nil {
+				t.Fatal(err)
+			}
+			if GetLevel() != c.level || debugSetting() != c.debug {
+				t.Errorf("level %v, debug %v, want %v, %v", GetLevel(), debugSetting(), c.level, c.debug)
+			}
+			l := NewLogger("flags", true)
+			l.Debug("debug line")
+			l.Info("info line")
+			l.Warn("warn line")
+			got := buf.String()
+			for _, s := range c.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("output %q does not contain %q", got, s)
+				}
+			}
+			for _, s := range c.missing {
+				if strings.Contains(got, s) {
+					t.Errorf("output %q contains %q", got, s)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterFlagsInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"-log-level=loud"},
+		{"-log-format=xml"},
+		{"-verbose=maybe"},
+		{"-log-file=" + filepath.Join(t.TempDir(), "missing", "out.log")},
+	} {
+		keepSettings(t)
+		fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		RegisterFlags(fs)
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestRegisterFlagsFile(t *testing.T) {
+	keepSettings(t)
+	t.Cleanup(ResetOutput)
+	path := filepath.Join(t.TempDir(), "out.log")
+	fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+	RegisterFlags(fs)
+	if err := fs.Parse([]string{"-log-file", path}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fs.Lookup("log-file").Value.String(); got != path {
+		t.Errorf("log-file = %q, want %q", got, path)
+	}
+	NewLogger("flags", false).Info("to the file")
+	ResetOutput()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "to the file") {
+		t.Errorf("file = %q, want the entry", b)
+	}
+}
+
+func TestValueTypes(t *testing.T) {
+	for _, c := range []struct {
+		v    Value
+		want string
+	}{
+		{LevelValue(), "level"},
+		{FormatValue(), "format"},
+		{FileValue(), "string"},
+		{VerboseValue(), "bool"},
+	} {
+		if got := c.v.Type(); got != c.want {
+			t.Errorf("%T.Type() = %q, want %q", c.v, got, c.want)
+		}
+	}
+}
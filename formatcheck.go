@@ -0,0 +1,70 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var formatCheck int32
+
+var formatWarned = struct {
+	sync.Mutex
+	sites map[string]bool
+}{sites: map[string]bool{}}
+
+// verbPattern matches the common fmt verbs, without the space flag so
+// "50% off" is not mistaken for one.
+var verbPattern = regexp.MustCompile(`%[-+#0]*(\d+|\*)?(\.(\d+|\*)?)?[vsdqxXftTwp]`)
+
+// SetFormatCheck enables or disables checking log calls for format
+// mistakes: a format verb in a call that is not an *f function, or a
+// verb and argument count mismatch in one that is. Each mistake logs a
+// warning, once per call site. The Development profile enables it.
+func SetFormatCheck(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&formatCheck, v)
+}
+
+func formatChecking() bool {
+	return atomic.LoadInt32(&formatCheck) == 1
+}
+
+// checkArgs warns if a string argument of a non-f call has format verbs.
+func (l *Logger) checkArgs(a []interface{}) {
+	for _, v := range a {
+		if s, ok := v.(string); ok && verbPattern.MatchString(s) {
+			l.formatWarning(fmt.Sprintf("log: %q has a format verb but is not logged with an *f function", s))
+			return
+		}
+	}
+}
+
+// checkFormat warns if formatting f produced fmt's %!verb errors.
+func (l *Logger) checkFormat(f, msg string) {
+	if strings.Contains(msg, "%!") && !strings.Contains(f, "%!") {
+		l.formatWarning(fmt.Sprintf("log: bad format %q: %s", f, msg))
+	}
+}
+
+// formatWarning logs msg once per call site. It bypasses output, so
+// the warning is not itself checked.
+func (l *Logger) formatWarning(msg string) {
+	site := getCaller()
+	formatWarned.Lock()
+	warned := formatWarned.sites[site]
+	formatWarned.sites[site] = true
+	formatWarned.Unlock()
+	if warned || !defaultLogger.levelEnabled(WarnLevel) {
+		return
+	}
+	if site != "" && !callerEnabled(defaultLogger.isDebug()) {
+		msg += " at " + site
+	}
+	defaultLogger.write(WarnLevel, msg)
+}
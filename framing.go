@@ -0,0 +1,65 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Framing selects how entries are delimited in the output, independent
+// of the format.
+type Framing string
+
+const (
+	// FramingNewline ends each entry with a newline, as NDJSON. This
+	// is the default.
+	FramingNewline Framing = "newline"
+	// FramingLengthPrefix writes each entry, without the newline,
+	// after its length as a 4 byte big-endian integer.
+	FramingLengthPrefix Framing = "length"
+	// FramingNUL ends each entry with a NUL byte instead of a
+	// newline.
+	FramingNUL Framing = "nul"
+)
+
+// SetFraming sets how entries are delimited in the output, for
+// collectors that read entries from a pipe in a particular framing.
+func SetFraming(f Framing) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.framing = f
+}
+
+// ParseFraming parses a framing name.
+func ParseFraming(s string) (Framing, error) {
+	switch f := Framing(strings.ToLower(strings.TrimSpace(s))); f {
+	case FramingNewline, FramingLengthPrefix, FramingNUL:
+		return f, nil
+	case "ndjson":
+		return FramingNewline, nil
+	}
+	return FramingNewline, fmt.Errorf("unknown log framing %q", s)
+}
+
+// frame applies the framing to the encoded entry in o.buf. Encoders
+// end entries with a newline, but custom ones may not, so the newline
+// is only replaced if there is one.
+func (o *output) frame() {
+	switch o.framing {
+	case FramingLengthPrefix:
+		b := bytes.TrimSuffix(o.buf.Bytes(), []byte("\n"))
+		o.frameBuf.Reset()
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+		o.frameBuf.Write(n[:])
+		o.frameBuf.Write(b)
+		o.buf, o.frameBuf = o.frameBuf, o.buf
+	case FramingNUL:
+		if b := o.buf.Bytes(); len(b) > 0 && b[len(b)-1] == '\n' {
+			b[len(b)-1] = 0
+		} else {
+			o.buf.WriteByte(0)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+// rawEncoder writes the message as it is, with no newline.
+type rawEncoder struct{}
+
+func (rawEncoder) Encode(b *bytes.Buffer, e *Entry) {
+	b.WriteString(e.Message)
+}
+
+func TestFraming(t *testing.T) {
+	defer func() {
+		SetFraming(FramingNewline)
+		SetFormat(FormatText)
+		ResetOutput()
+	}()
+	for _, c := range []struct {
+		framing Framing
+		enc     Encoder
+		msg     string
+		want    string
+	}{
+		{FramingLengthPrefix, nil, "hi", "\x00\x00\x00\x0dnfo     |  hi"},
+		{FramingNUL, nil, "hi", "nfo     |  hi\x00"},
+		{FramingLengthPrefix, rawEncoder{}, "", "\x00\x00\x00\x00"},
+		{FramingLengthPrefix, rawEncoder{}, "abc", "\x00\x00\x00\x03abc"},
+		{FramingNUL, rawEncoder{}, "", "\x00"},
+		{FramingNUL, rawEncoder{}, "abc", "abc\x00"},
+	} {
+		var buf bytes.Buffer
+		SetOutput(&buf)
+		SetFraming(c.framing)
+		if c.enc != nil {
+			SetEncoder(c.enc)
+		} else {
+			SetFormat(FormatText)
+		}
+		NewLogger("nfo", false).Info(c.msg)
+		if got := buf.String(); got != c.want {
+			t.Errorf("%s framing of %q = %q, want %q", c.framing, c.msg, got, c.want)
+		}
+	}
+}
@@ -2,4 +2,4 @@ module github.com/dangersalad/go-log
 
 // vgo: no requirements found in Gopkg.lock
 
-go 1.13
+go 1.21
@@ -0,0 +1,61 @@
+package log
+
+// Group logs title with the default logger and groups its subsequent
+// entries under it. See Logger.Group.
+func Group(title string) (end func()) {
+	return defaultLogger.Group(title)
+}
+
+// Group logs title at the info level, then indents the logger's
+// subsequent entries in the text format until end is called. In JSON,
+// entries get a group field with the titles of the open groups joined
+// by "/". Groups nest, and end must be called in reverse order.
+//
+//	end := logger.Group("migrating schema")
+//	defer end()
+//
+// The group applies to the logger and loggers derived from it, from
+// every goroutine, so it is meant for sequential steps such as a
+// command line tool's output. The logger it was derived from is not
+// affected.
+func (l *Logger) Group(title string) (end func()) {
+	l.Info(title)
+	ov := l.ov()
+	ov.groupMu.Lock()
+	depth := len(ov.groups)
+	groups := make([]string, depth+1)
+	copy(groups, ov.groups)
+	groups[depth] = title
+	ov.groups = groups
+	ov.groupMu.Unlock()
+	return func() {
+		ov.groupMu.Lock()
+		defer ov.groupMu.Unlock()
+		if len(ov.groups) > depth {
+			ov.groups = ov.groups[:depth:depth]
+		}
+	}
+}
+
+func (l *Logger) openGroups() []string {
+	return l.ov().openGroups()
+}
+
+// openGroups returns the groups open on the parents, outermost first,
+// followed by those opened on ov.
+func (ov *overrides) openGroups() []string {
+	ov.groupMu.Lock()
+	own := ov.groups
+	ov.groupMu.Unlock()
+	if ov.parent == nil {
+		return own
+	}
+	inherited := ov.parent.openGroups()
+	if len(own) == 0 {
+		return inherited
+	}
+	if len(inherited) == 0 {
+		return own
+	}
+	return append(inherited[:len(inherited):len(inherited)], own...)
+}
@@ -2,10 +2,17 @@ package log
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,18 +21,29 @@ var DefaultPathLogBlacklist = regexp.MustCompile(`/ping|/healthz`)
 
 type statusWriter struct {
 	http.ResponseWriter
-	status int
-	body   string
+	status      int
+	wroteHeader bool
+	// bytes is the length of the body written so far.
+	bytes int64
+}
+
+var statusWriterPool = sync.Pool{
+	New: func() interface{} { return new(statusWriter) },
 }
 
 func (w *statusWriter) WriteHeader(status int) {
-	w.status = status
+	// only the first call counts, as with net/http
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
 	w.ResponseWriter.WriteHeader(status)
 }
 
-func (w *statusWriter) Write(b []byte) (int, error) {
-	w.body = string(b)
-	return w.ResponseWriter.Write(b)
+func (w *statusWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
 }
 
 func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
@@ -35,42 +53,389 @@ func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return nil, nil, fmt.Errorf("hijacking not supported")
 }
 
+func (w *statusWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // HTTPHandler returns a handler that will log out request data.
 //
 // If the logger is nil, the default "main" logger is
 // used.
 //
 // blacklist can be nil, in which case all calls are logged
-func HTTPHandler(h http.Handler, logger *Logger, blacklist *regexp.Regexp) http.Handler {
+//
+// The ResponseWriter passed to h implements http.Hijacker, http.Pusher
+// and http.Flusher, passing calls through when the underlying writer
+// supports them. Hijack and Push return an error otherwise, and Flush
+// does nothing. It also has an Unwrap method for
+// http.ResponseController.
+//
+// opts can change what is logged, see HTTPOption.
+//
+// The request context carries the logger, see FromContext, with the
+// request's method, path, route and ID, from the X-Request-ID header,
+// added to Die entries logged through it with DieContext.
+//
+// If the client disconnects, or the request context is otherwise
+// canceled, before h returns, the entry gets a canceled=true field.
+// With WithTimings, it also gets an after_cancel field with how long h
+// kept running afterwards.
+func HTTPHandler(h http.Handler, logger *Logger, blacklist *regexp.Regexp, opts ...HTTPOption) http.Handler {
 
 	if logger == nil {
 		logger = defaultLogger
 	}
+	o := newHTTPOptions(opts)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// don't log for certain paths, and don't pay for wrapping
+		// them either
+		if blacklist != nil && blacklist.MatchString(r.URL.Path) {
+			h.ServeHTTP(w, r)
+			return
+		}
 		start := time.Now()
-		sw := &statusWriter{
-			ResponseWriter: w,
-			status:         200,
+		ctx := r.Context()
+		var cps *checkpoints
+		if o.timings {
+			cps = &checkpoints{start: start}
+			ctx = context.WithValue(ctx, checkpointsKey{}, cps)
+		}
+		r = r.WithContext(ctx)
+		var id string
+		if o.requestID {
+			id = r.Header.Get(RequestIDHeader)
+			if id == "" {
+				id = NewID()
+				// r is a copy, but shares its header with the
+				// caller's request, which must not change.
+				r.Header = r.Header.Clone()
+				if r.Header == nil {
+					r.Header = http.Header{}
+				}
+				r.Header.Set(RequestIDHeader, id)
+			}
+			w.Header().Set(RequestIDHeader, id)
 		}
+		// The logger refers to r for the route, so r has to be the
+		// request that is served.
+		*r = *r.WithContext(NewContext(ctx, o.requestLogger(ctx, logger, r)))
+		// Timing the cancellation costs a few allocations, so it is
+		// only done with WithTimings.
+		var canceledAt atomic.Int64
+		stop := func() bool { return false }
+		if o.timings {
+			stop = context.AfterFunc(ctx, func() {
+				canceledAt.Store(time.Now().UnixNano())
+			})
+		}
+		sw := statusWriterPool.Get().(*statusWriter)
+		*sw = statusWriter{ResponseWriter: w, status: 200}
 		h.ServeHTTP(sw, r)
 		// get the diff and parse that time
-		diff := time.Now().Sub(start)
-		// don't log for certain paths
-		if blacklist != nil && blacklist.MatchString(r.URL.Path) {
+		diff := time.Since(start)
+		stop()
+		c := sw.status
+		n := sw.bytes
+		*sw = statusWriter{}
+		statusWriterPool.Put(sw)
+		if o.w3c != nil {
+			o.w3c.write(o, w3cRequest{r: r, start: start, status: c, bytes: n, took: diff})
+			return
+		}
+		lvl := DebugLevel
+		if c >= 500 {
+			lvl = InfoLevel
+		}
+		l, lvl := o.logger(logger, lvl, diff)
+		if !l.enabled(lvl) {
 			return
 		}
-		diffStr := diff.String()
-		if diff > time.Second {
-			diffStr = diff.Truncate(time.Millisecond).String()
-		} else if diff > time.Millisecond {
-			diffStr = fmt.Sprintf("%0.3fms", float64(diff.Nanoseconds())/10000000.0)
+		if o.sampler != nil && lvl < WarnLevel {
+			keep, rate := o.sampler.sample(l, c)
+			if !keep {
+				return
+			}
+			if rate < 1 {
+				l = l.with(Float64(SampleRateKey, rate))
+			}
+		}
+		l = o.route(l, r)
+		if id != "" {
+			l = l.with(Str(RequestIDKey, id))
 		}
-		switch c := sw.status; true {
-		case c >= 500:
-			logger.Infof("%s %s [%d] (%s)", r.Method, r.URL, c, diffStr)
-		default:
-			logger.Debugf("%s %s [%d] (%s)", r.Method, r.URL, c, diffStr)
+		if cps != nil {
+			l = l.with(cps.fields()...)
 		}
+		if o.numeric {
+			l = l.with(numericFields(c, diff)...)
+		}
+		if o.conn {
+			l = l.with(connFields(r.Proto, r.Host, r.TLS)...)
+		}
+		if ctx.Err() == context.Canceled {
+			l = l.with(canceledFields(start.Add(diff), canceledAt.Load())...)
+		}
+		l.writeFields(lvl, accessMessage(r.Method, o.logURL(r.URL), c, diff), nil)
 	})
 }
+
+// CanceledKey is the field key HTTPHandler uses to mark requests whose
+// client went away before the handler returned.
+const CanceledKey = "canceled"
+
+// canceledFields marks a canceled request, with how long the handler
+// kept going after the cancellation if it was timed, at is then the
+// UnixNano time of the cancellation.
+func canceledFields(end time.Time, at int64) []Field {
+	if at == 0 {
+		return []Field{Bool(CanceledKey, true)}
+	}
+	after := end.Sub(time.Unix(0, at))
+	if after < 0 {
+		after = 0
+	}
+	return []Field{Bool(CanceledKey, true), Dur("after_cancel", after)}
+}
+
+// HTTPOption configures HTTPHandler and Transport.
+type HTTPOption func(*httpOptions)
+
+type httpOptions struct {
+	timings     bool
+	slow        time.Duration
+	routeField  func(*http.Request) string
+	routePrefix func(*http.Request) string
+	queryDeny   map[string]bool
+	queryAllow  map[string]bool
+	pathMask    *regexp.Regexp
+	w3c         *w3cLog
+	numeric     bool
+	conn        bool
+	sampler     *accessSampler
+	requestID   bool
+}
+
+// logger returns the logger and level for a request that took diff,
+// escalating slow requests.
+func (o *httpOptions) logger(l *Logger, lvl Level, diff time.Duration) (*Logger, Level) {
+	if o.slow > 0 && diff > o.slow {
+		if lvl < WarnLevel {
+			lvl = WarnLevel
+		}
+		l = l.with(Any("slow", true))
+	}
+	return l, lvl
+}
+
+// route applies the route field and prefix options to l.
+func (o *httpOptions) route(l *Logger, r *http.Request) *Logger {
+	if o.routePrefix != nil {
+		if p := o.routePrefix(r); p != "" {
+			l = l.withPrefix(p)
+		}
+	}
+	if o.routeField != nil {
+		if route := o.routeField(r); route != "" {
+			l = l.with(Any(RouteKey, route))
+		}
+	}
+	return l
+}
+
+// RequestIDKey is the field key for the request ID.
+const RequestIDKey = "request_id"
+
+// RequestIDHeader is the request header the request ID is read from.
+const RequestIDHeader = "X-Request-ID"
+
+// WithRequestID gives requests without an X-Request-ID header a new ID
+// from the ID generator, see SetIDGenerator, setting the header on the
+// request the handler gets so it and outbound calls can pass it on.
+// The caller's request is left as it is. The ID is
+// returned in the response's X-Request-ID header and added as a
+// request_id field to the access log entry and to every entry logged
+// through the request context's logger.
+func WithRequestID() HTTPOption {
+	return func(o *httpOptions) {
+		o.requestID = true
+	}
+}
+
+// requestLogger returns the logger HTTPHandler puts in the request
+// context: the context's logger, or l, with the request's method,
+// path, ID and route added to Die entries, so a fatal error while
+// serving it can be traced back to the request.
+func (o *httpOptions) requestLogger(ctx context.Context, l *Logger, r *http.Request) *Logger {
+	if cl, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		l = cl
+	}
+	fs := []Field{Str("method", r.Method), Str("path", o.logURL(r.URL).Path)}
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		if o.requestID {
+			l = l.with(Str(RequestIDKey, id))
+		} else {
+			fs = append(fs, Str(RequestIDKey, id))
+		}
+	}
+	if o.routeField != nil {
+		// The route is usually only known once a router has matched
+		// the request, so look it up when Die is called.
+		fs = append(fs, Field{kind: providerKind, value: func() Fields {
+			if route := o.routeField(r); route != "" {
+				return Fields{RouteKey: route}
+			}
+			return nil
+		}})
+	}
+	return l.withFatal(fs...)
+}
+
+func newHTTPOptions(opts []HTTPOption) *httpOptions {
+	o := &httpOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithTimings adds a breakdown of where the time went to each entry.
+//
+// For Transport, the DNS, connect, TLS handshake and first response
+// byte times are traced with net/http/httptrace. For HTTPHandler, the
+// times of any Checkpoint calls made with the request context are
+// added, and how long canceled requests ran after the cancellation.
+func WithTimings() HTTPOption {
+	return func(o *httpOptions) {
+		o.timings = true
+	}
+}
+
+func formatDuration(diff time.Duration) string {
+	var buf [32]byte
+	return string(appendDuration(buf[:0], diff))
+}
+
+func appendDuration(b []byte, diff time.Duration) []byte {
+	if diff > time.Second {
+		return append(b, diff.Truncate(time.Millisecond).String()...)
+	} else if diff > time.Millisecond {
+		b = strconv.AppendFloat(b, float64(diff.Nanoseconds())/1e6, 'f', 3, 64)
+		return append(b, "ms"...)
+	}
+	return append(b, diff.String()...)
+}
+
+// accessMessage renders "GET /path [200] (1.234ms)", as Sprintf would,
+// with a single allocation in the common case.
+func accessMessage(method string, u *url.URL, status int, diff time.Duration) string {
+	var buf [128]byte
+	b := append(buf[:0], method...)
+	b = append(b, ' ')
+	if plainPath(u) {
+		b = append(b, u.EscapedPath()...)
+	} else {
+		b = append(b, u.String()...)
+	}
+	b = append(b, " ["...)
+	b = strconv.AppendInt(b, int64(status), 10)
+	b = append(b, "] ("...)
+	b = appendDuration(b, diff)
+	b = append(b, ')')
+	return string(b)
+}
+
+// plainPath reports whether u.String() is just the escaped path.
+func plainPath(u *url.URL) bool {
+	return u.Scheme == "" && u.Opaque == "" && u.User == nil && u.Host == "" &&
+		u.RawQuery == "" && !u.ForceQuery && u.Fragment == "" &&
+		strings.HasPrefix(u.Path, "/") && !strings.Contains(u.Path, ":")
+}
+
+// WithNumericFields adds status, status_class and duration_ms fields
+// to each entry, so tools computing percentiles from JSON logs don't
+// have to parse the message. status_class is the hundreds digit of the
+// status, such as 5 for a 503, and duration_ms a float.
+func WithNumericFields() HTTPOption {
+	return func(o *httpOptions) {
+		o.numeric = true
+	}
+}
+
+func numericFields(status int, diff time.Duration) []Field {
+	return []Field{
+		Int("status", status),
+		Int("status_class", status/100),
+		Float64("duration_ms", float64(diff)/float64(time.Millisecond)),
+	}
+}
+
+// WithConnectionFields adds proto and host fields to each entry, and
+// tls_version and tls_cipher for TLS connections, for auditing which
+// clients still use old protocols. For Transport, the fields describe
+// the response.
+func WithConnectionFields() HTTPOption {
+	return func(o *httpOptions) {
+		o.conn = true
+	}
+}
+
+func connFields(proto, host string, cs *tls.ConnectionState) []Field {
+	fs := []Field{Str("proto", proto), Str("host", host)}
+	if cs != nil {
+		fs = append(fs,
+			Str("tls_version", tls.VersionName(cs.Version)),
+			Str("tls_cipher", tls.CipherSuiteName(cs.CipherSuite)),
+		)
+	}
+	return fs
+}
+
+// WithSlowThreshold logs requests that take longer than d at the warn
+// level, or higher, with a slow=true field.
+func WithSlowThreshold(d time.Duration) HTTPOption {
+	return func(o *httpOptions) {
+		o.slow = d
+	}
+}
+
+// RouteKey is the field key used by WithRouteField.
+const RouteKey = "route"
+
+// WithRouteField adds a route field with the value returned by fn,
+// such as a mux route template, so access logs can be grouped by route
+// rather than by raw paths. Nothing is added if fn returns "".
+//
+// fn is called after the request is served, so routers that record the
+// matched pattern on the request can be used:
+//
+//	log.WithRouteField(func(r *http.Request) string { return r.Pattern })
+func WithRouteField(fn func(*http.Request) string) HTTPOption {
+	return func(o *httpOptions) {
+		o.routeField = fn
+	}
+}
+
+// WithRoutePrefix uses the value returned by fn as the logger prefix
+// for the access log entry, truncated like any other prefix. The
+// logger's prefix is kept if fn returns "". Like WithRouteField, fn is
+// called after the request is served.
+func WithRoutePrefix(fn func(*http.Request) string) HTTPOption {
+	return func(o *httpOptions) {
+		o.routePrefix = fn
+	}
+}
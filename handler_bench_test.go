@@ -0,0 +1,87 @@
+package log
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// nopResponseWriter discards the response, so benchmarks measure the
+// middleware rather than a recorder.
+type nopResponseWriter struct {
+	h http.Header
+}
+
+func (w *nopResponseWriter) Header() http.Header         { return w.h }
+func (w *nopResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *nopResponseWriter) WriteHeader(int)             {}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+// benchHandler returns a logger writing to io.Discard, with debug, and
+// so access logging of successful requests, on or off.
+func benchHandler(b *testing.B, debug bool) *Logger {
+	SetOutput(io.Discard)
+	b.Cleanup(ResetOutput)
+	l := NewLogger("http", false)
+	l.SetDebug(debug)
+	return l
+}
+
+func benchServe(b *testing.B, h http.Handler, path string) {
+	r := httptest.NewRequest("GET", path, nil)
+	w := &nopResponseWriter{h: http.Header{}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, r)
+	}
+}
+
+func BenchmarkHTTPHandlerBlacklisted(b *testing.B) {
+	l := benchHandler(b, true)
+	benchServe(b, HTTPHandler(okHandler, l, DefaultPathLogBlacklist), "/healthz")
+}
+
+func BenchmarkHTTPHandlerQuiet(b *testing.B) {
+	l := benchHandler(b, false)
+	benchServe(b, HTTPHandler(okHandler, l, DefaultPathLogBlacklist), "/api/users?page=2")
+}
+
+func BenchmarkHTTPHandlerLogged(b *testing.B) {
+	l := benchHandler(b, true)
+	benchServe(b, HTTPHandler(okHandler, l, DefaultPathLogBlacklist), "/api/users?page=2")
+}
+
+// benchServeParallel serves path with h from GOMAXPROCS goroutines,
+// as a server would, each with its own request and response.
+func benchServeParallel(b *testing.B, h http.Handler, path string) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := httptest.NewRequest("GET", path, nil)
+		w := &nopResponseWriter{h: http.Header{}}
+		for pb.Next() {
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+// BenchmarkHTTPHandlerParallelBare is the handler without HTTPHandler,
+// the cost the middleware's numbers below should be read against.
+func BenchmarkHTTPHandlerParallelBare(b *testing.B) {
+	benchServeParallel(b, okHandler, "/api/users?page=2")
+}
+
+func BenchmarkHTTPHandlerParallelQuiet(b *testing.B) {
+	l := benchHandler(b, false)
+	benchServeParallel(b, HTTPHandler(okHandler, l, DefaultPathLogBlacklist), "/api/users?page=2")
+}
+
+func BenchmarkHTTPHandlerParallelLogged(b *testing.B) {
+	l := benchHandler(b, true)
+	benchServeParallel(b, HTTPHandler(okHandler, l, DefaultPathLogBlacklist), "/api/users?page=2")
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerPush(t *testing.T) {
+	var (
+		proto string
+		err   error
+	)
+	ts := httptest.NewUnstartedServer(HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.Proto
+		// Go's HTTP/2 client turns push off, so push a relative
+		// target, which only the HTTP/2 server's Push rejects as
+		// invalid, to see that the call reaches it.
+		err = w.(http.Pusher).Push("style.css", nil)
+	}), nil, nil))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	res, e := ts.Client().Get(ts.URL)
+	if e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+	if proto != "HTTP/2.0" {
+		t.Fatalf("served over %s, want HTTP/2.0", proto)
+	}
+	if err == nil || errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Push returned %v, want the HTTP/2 server's error", err)
+	}
+}
+
+func TestHandlerPushNotSupported(t *testing.T) {
+	var err error
+	h := HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err = w.(http.Pusher).Push("/style.css", nil)
+	}), nil, nil)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Push returned %v, want http.ErrNotSupported", err)
+	}
+}
+
+func TestHandlerFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	h := HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+	}), nil, nil)
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !rec.Flushed {
+		t.Error("Flush did not reach the underlying writer")
+	}
+	if err := http.NewResponseController(rec).Flush(); err != nil {
+		t.Errorf("ResponseController.Flush: %v", err)
+	}
+}
+
+func TestHandlerHijack(t *testing.T) {
+	ts := httptest.NewServer(HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		rw.Flush()
+	}), nil, nil))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	if string(b) != "hijacked" {
+		t.Errorf("body = %q, want the hijacked connection's response", b)
+	}
+}
+
+func TestHandlerHijackNotSupported(t *testing.T) {
+	var err error
+	h := HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, err = w.(http.Hijacker).Hijack()
+	}), nil, nil)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if err == nil {
+		t.Error("Hijack on a recorder succeeded")
+	}
+}
+
+func TestHandlerRequestIDLeavesRequest(t *testing.T) {
+	SetOutput(io.Discard)
+	defer ResetOutput()
+	var seen string
+	h := HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get(RequestIDHeader)
+	}), nil, nil, WithRequestID())
+
+	r := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if seen == "" || rec.Header().Get(RequestIDHeader) != seen {
+		t.Errorf("handler saw ID %q, response has %q, want the same new ID", seen, rec.Header().Get(RequestIDHeader))
+	}
+	if v := r.Header.Get(RequestIDHeader); v != "" {
+		t.Errorf("caller's request got header %s: %q", RequestIDHeader, v)
+	}
+
+	r.Header.Set(RequestIDHeader, "given")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if seen != "given" {
+		t.Errorf("handler saw ID %q, want the request's own", seen)
+	}
+}
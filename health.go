@@ -0,0 +1,88 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// SinkHealth is the state of a log destination.
+type SinkHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	// Written and Dropped count the entries written and the entries
+	// lost to write errors.
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	// QueueDepth is the number of entries waiting to be written: those
+	// an HTTPWriter queued while backing off and those in a
+	// SpoolWriter's spool. It is always 0 for sinks that write
+	// synchronously.
+	QueueDepth    int        `json:"queue_depth"`
+	LastWrite     *time.Time `json:"last_write,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+// Health is the state of the logging pipeline.
+type Health struct {
+	Healthy bool         `json:"healthy"`
+	Sinks   []SinkHealth `json:"sinks"`
+}
+
+// GetHealth returns the state of the logging pipeline. A sink is
+// unhealthy if its most recent write failed.
+func GetHealth() Health {
+	h := Health{Healthy: true}
+	for _, s := range allOutputs() {
+		sh := s.health()
+		h.Healthy = h.Healthy && sh.Healthy
+		h.Sinks = append(h.Sinks, sh)
+	}
+	return h
+}
+
+// queueReporter is implemented by writers that hold entries back,
+// to report how many are waiting.
+type queueReporter interface {
+	queueDepth() int
+}
+
+func (o *output) health() SinkHealth {
+	o.mu.Lock()
+	st := o.stats
+	sh := SinkHealth{
+		Name:    o.name(),
+		Healthy: st.lastErr == nil || st.lastWrite.After(st.errTime),
+		Written: st.written,
+		Dropped: st.dropped,
+	}
+	w := o.w
+	o.mu.Unlock()
+	// Asked without the output locked, as writers take their own lock
+	// while writing.
+	if q, ok := w.(queueReporter); ok {
+		sh.QueueDepth = q.queueDepth()
+	}
+	if !st.lastWrite.IsZero() {
+		sh.LastWrite = &st.lastWrite
+	}
+	if st.lastErr != nil {
+		sh.LastError = st.lastErr.Error()
+		sh.LastErrorTime = &st.errTime
+	}
+	return sh
+}
+
+// HealthHandler returns a handler that reports GetHealth as JSON, with
+// a 503 status if any sink is unhealthy, for use as a readiness probe.
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := GetHealth()
+		w.Header().Set("Content-Type", "application/json")
+		if !h.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(h)
+	})
+}
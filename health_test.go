@@ -0,0 +1,57 @@
+package log
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("unreachable")
+}
+
+func TestHealthQueueDepth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+	hw := NewHTTPWriter(ts.URL, nil)
+	SetOutput(hw)
+	defer ResetOutput()
+	l := NewLogger("health", false)
+	for i := 0; i < 3; i++ {
+		l.Info("queued")
+	}
+	if got := GetHealth().Sinks[0].QueueDepth; got != 3 {
+		t.Errorf("HTTPWriter queue depth = %d, want 3", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "spool")
+	sw, err := NewSpoolWriter(failingWriter{}, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetOutput(sw)
+	for i := 0; i < 2; i++ {
+		l.Info("spooled")
+	}
+	if got := GetHealth().Sinks[0].QueueDepth; got != 2 {
+		t.Errorf("SpoolWriter queue depth = %d, want 2", got)
+	}
+	sw.Close()
+
+	sw, err = NewSpoolWriter(hw, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+	SetOutput(sw)
+	if got := GetHealth().Sinks[0].QueueDepth; got != 5 {
+		t.Errorf("reopened SpoolWriter over HTTPWriter queue depth = %d, want 5", got)
+	}
+}
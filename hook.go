@@ -0,0 +1,97 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HookEntry is the part of an entry a Hook can see and change. Changes
+// to Time and Message are kept; Level and Prefix are only for reading.
+type HookEntry struct {
+	Time    time.Time
+	Level   Level
+	Prefix  string
+	Message string
+
+	// added are fields added by hooks, appended after the entry's own.
+	added []Field
+}
+
+// AddField adds a field to the entry.
+func (h *HookEntry) AddField(key string, value interface{}) {
+	h.added = append(h.added, Any(key, value))
+}
+
+// Hook is called with every entry that will be logged, before it is
+// encoded, and may change the message or add fields. It is a lighter
+// alternative to writing a whole encoder:
+//
+//	log.AddHook(func(h *log.HookEntry) {
+//		h.AddField("local_time", h.Time.In(loc).Format(time.Kitchen))
+//	})
+//
+// Hooks run on the logging goroutine and must be safe for concurrent
+// use.
+type Hook func(h *HookEntry)
+
+var (
+	hooksMu sync.Mutex
+	// hooks holds a []Hook, replaced on change.
+	hooks atomic.Value
+)
+
+// AddHook adds a hook run for all loggers, after any added before.
+func AddHook(h Hook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	old, _ := hooks.Load().([]Hook)
+	hs := make([]Hook, len(old), len(old)+1)
+	copy(hs, old)
+	hooks.Store(append(hs, h))
+}
+
+// ClearHooks removes all hooks.
+func ClearHooks() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks.Store([]Hook(nil))
+}
+
+func runHooks(e *entry) {
+	hs, _ := hooks.Load().([]Hook)
+	if len(hs) == 0 {
+		return
+	}
+	h := HookEntry{
+		Time:    e.time,
+		Level:   e.level,
+		Prefix:  e.prefix,
+		Message: e.message,
+	}
+	for _, fn := range hs {
+		fn(&h)
+	}
+	e.time = h.Time
+	e.message = h.Message
+	if len(h.added) > 0 {
+		fs := make([]Field, 0, len(e.fields)+len(h.added))
+		e.fields = append(append(fs, e.fields...), h.added...)
+	}
+}
+
+// emit sends a complete entry on its way to the output.
+func emit(e *entry) {
+	if !suppress(e) || !sample(e) {
+		return
+	}
+	addBuildInfo(e)
+	numberEntry(e)
+	addEntryID(e)
+	resolveLazy(e)
+	runHooks(e)
+	e.fields = dedupFields(e.fields)
+	checkSchema(e)
+	recent.add(e)
+	writeAll(e)
+}
@@ -0,0 +1,293 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrThrottled is returned by HTTPWriter when the collector has asked
+// it to back off and its queue is full.
+var ErrThrottled = errors.New("log: throttled by collector")
+
+// DefaultHTTPQueueLimit is the number of entries an HTTPWriter queues
+// while backing off by default.
+const DefaultHTTPQueueLimit = 1000
+
+// DefaultHTTPTimeout is the request timeout of an HTTPWriter's default
+// client. Writes block the output, so a collector that stops answering
+// must not hold them up for longer.
+const DefaultHTTPTimeout = 10 * time.Second
+
+const (
+	minHTTPBackoff = time.Second
+	maxHTTPBackoff = time.Minute
+)
+
+// HTTPWriter posts each write to a URL, for log collectors and
+// webhooks that take entries over HTTP. Wrap it in a SpoolWriter to
+// keep entries while the collector is unreachable.
+//
+// When the collector responds with 429 Too Many Requests or 503
+// Service Unavailable, the writer stops sending for the time given by
+// the Retry-After header, or for a jittered exponential backoff if
+// there is none. Entries written in the meantime are queued, up to the
+// queue limit, and sent in order once the wait is over. Each throttle
+// is reported to the error handler; see SetErrorHandler. If the
+// collector can't be reached while the queue is sent, the queue is
+// kept and the writer backs off again. Queued entries the collector
+// answers with another error status are dropped and reported.
+type HTTPWriter struct {
+	url    string
+	client *http.Client
+	creds  Credentials
+	// header is the request header the credential is sent in, and
+	// scheme what comes before it, such as "Bearer".
+	header, scheme string
+	ctype          string
+	// encoding is the Content-Encoding of bodies compressed by comp.
+	encoding string
+	comp     Compressor
+
+	mu sync.Mutex
+	// queue holds entries written while backing off until retryAt.
+	queue    [][]byte
+	limit    int
+	retryAt  time.Time
+	failures int
+}
+
+// NewHTTPWriter returns a writer posting to url. If creds is not nil,
+// each request has an "Authorization: Bearer" header with the current
+// credential. A request that is rejected with 401 is retried once with
+// the credential asked for again, after dropping it if creds caches
+// it, as FileCredentials and CachedCredentials do. If the credential
+// can't be had, the entry is dropped and the error returned or
+// reported, as for an error status.
+func NewHTTPWriter(url string, creds Credentials) *HTTPWriter {
+	return &HTTPWriter{
+		url:    url,
+		client: &http.Client{Timeout: DefaultHTTPTimeout},
+		creds:  creds,
+		header: "Authorization",
+		scheme: "Bearer",
+		ctype:  "application/x-ndjson",
+		limit:  DefaultHTTPQueueLimit,
+	}
+}
+
+// SetClient sets the client used for requests. The default is a
+// client with a timeout of DefaultHTTPTimeout. Writes are sent while
+// the output is locked, so c should have a timeout too, or every log
+// call waits on a collector that hangs.
+func (h *HTTPWriter) SetClient(c *http.Client) {
+	h.client = c
+}
+
+// SetCredentialHeader sends the credential in header, after scheme and
+// a space if scheme is not empty, such as "X-Api-Key" with no scheme.
+func (h *HTTPWriter) SetCredentialHeader(header, scheme string) {
+	h.header, h.scheme = header, scheme
+}
+
+// SetContentType sets the content type of requests. The default is
+// "application/x-ndjson".
+func (h *HTTPWriter) SetContentType(ctype string) {
+	h.ctype = ctype
+}
+
+// SetCompression compresses each request body with c and sends it
+// with encoding as its Content-Encoding, such as "gzip" with Gzip. A
+// nil c sends bodies as they are, which is the default.
+func (h *HTTPWriter) SetCompression(encoding string, c Compressor) {
+	h.encoding, h.comp = encoding, c
+}
+
+// SetQueueLimit sets the number of entries queued while backing off.
+// Writes beyond it fail with ErrThrottled. The default is
+// DefaultHTTPQueueLimit.
+func (h *HTTPWriter) SetQueueLimit(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.limit = n
+}
+
+// Queued returns the number of entries waiting for a backoff to end.
+func (h *HTTPWriter) Queued() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.queue)
+}
+
+func (h *HTTPWriter) queueDepth() int {
+	return h.Queued()
+}
+
+// Write posts p, returning an error if the request fails or the
+// response status is not 2xx. While backing off, p is queued instead.
+func (h *HTTPWriter) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if time.Now().Before(h.retryAt) {
+		return h.enqueue(p)
+	}
+	for len(h.queue) > 0 {
+		throttled, err := h.send(h.queue[0])
+		if throttled {
+			return h.enqueue(p)
+		}
+		var rej *rejectedError
+		if err != nil && !errors.As(err, &rej) {
+			// The collector could not be reached; keep the queue
+			// and try again after a backoff.
+			wait := h.delay("")
+			reportErrorAsync(&SinkError{
+				Sink: h.url,
+				Err:  fmt.Errorf("%w, retrying %d queued entries in %s", err, len(h.queue), wait.Round(time.Millisecond)),
+			})
+			return h.enqueue(p)
+		}
+		if err != nil {
+			// The collector rejected the entry; drop it rather than
+			// block the queue.
+			reportErrorAsync(&SinkError{Sink: h.url, Err: err})
+		}
+		h.queue[0] = nil
+		h.queue = h.queue[1:]
+	}
+	throttled, err := h.send(p)
+	if throttled {
+		return h.enqueue(p)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// enqueue keeps a copy of p to send after the backoff.
+func (h *HTTPWriter) enqueue(p []byte) (int, error) {
+	if len(h.queue) >= h.limit {
+		return 0, ErrThrottled
+	}
+	h.queue = append(h.queue, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// rejectedError is an error sending an entry that retrying would not
+// fix: the collector answered with an error status, the entry could
+// not be compressed, or there was no credential to send it with.
+type rejectedError struct {
+	err error
+}
+
+func (e *rejectedError) Error() string { return e.err.Error() }
+func (e *rejectedError) Unwrap() error { return e.err }
+
+// send posts p, reporting whether the collector throttled it. Errors
+// other than a *rejectedError mean the collector could not be reached.
+func (h *HTTPWriter) send(p []byte) (throttled bool, err error) {
+	if h.comp != nil {
+		if p, err = compress(h.comp, p); err != nil {
+			return false, &rejectedError{fmt.Errorf("log: compressing entry: %w", err)}
+		}
+	}
+	resp, err := h.post(p)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && h.creds != nil {
+		// Ask for the credential again; it may have been rotated.
+		resp.Body.Close()
+		if i, ok := h.creds.(invalidator); ok {
+			i.Invalidate()
+		}
+		resp, err = h.post(p)
+	}
+	if err != nil {
+		return false, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		h.backoff(resp)
+		return true, nil
+	}
+	h.failures = 0
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, &rejectedError{fmt.Errorf("log: %s returned %s", h.url, resp.Status)}
+	}
+	return false, nil
+}
+
+// backoff sets retryAt from the response's Retry-After header, or from
+// a jittered exponential backoff, and reports the throttle.
+func (h *HTTPWriter) backoff(resp *http.Response) {
+	wait := h.delay(resp.Header.Get("Retry-After"))
+	// Reported from another goroutine, as Write is called with the
+	// output locked and the handler may log.
+	reportErrorAsync(&SinkError{
+		Sink: h.url,
+		Err:  fmt.Errorf("%w: %s, retrying in %s", ErrThrottled, resp.Status, wait.Round(time.Millisecond)),
+	})
+}
+
+// delay sets retryAt from a Retry-After value, or if there is none,
+// from a jittered exponential backoff, and returns the wait.
+func (h *HTTPWriter) delay(retry string) time.Duration {
+	h.failures++
+	wait, ok := retryAfter(retry)
+	if !ok {
+		d := minHTTPBackoff << (h.failures - 1)
+		if d > maxHTTPBackoff || d <= 0 {
+			d = maxHTTPBackoff
+		}
+		// Full jitter: wait somewhere between d/2 and d.
+		wait = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	h.retryAt = time.Now().Add(wait)
+	return wait
+}
+
+// retryAfter parses a Retry-After value, in seconds or an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func (h *HTTPWriter) post(p []byte) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(p))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", h.ctype)
+	if h.comp != nil && h.encoding != "" {
+		req.Header.Set("Content-Encoding", h.encoding)
+	}
+	if h.creds != nil {
+		c, err := h.creds.Credential()
+		if err != nil {
+			return nil, &rejectedError{fmt.Errorf("log: getting credential: %w", err)}
+		}
+		if h.scheme != "" {
+			c = h.scheme + " " + c
+		}
+		req.Header.Set(h.header, c)
+	}
+	return h.client.Do(req)
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHTTPWriterKeepsQueueOnTransportError(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		got      []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			// Reset the connection without answering.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		default:
+			b, _ := io.ReadAll(r.Body)
+			got = append(got, string(b))
+		}
+	}))
+	defer ts.Close()
+	SetErrorHandler(func(error) {})
+	defer SetErrorHandler(nil)
+
+	h := NewHTTPWriter(ts.URL, nil)
+	if _, err := h.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if n := h.Queued(); n != 1 {
+		t.Fatalf("queued %d entries after a 429, want 1", n)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := h.Write([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if n := h.Queued(); n != 2 {
+		t.Fatalf("queued %d entries after a connection reset, want 2", n)
+	}
+
+	// End the backoff rather than wait it out.
+	h.mu.Lock()
+	h.retryAt = time.Time{}
+	h.mu.Unlock()
+	if _, err := h.Write([]byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if s := strings.Join(got, ","); s != "a,b,c" {
+		t.Errorf("collector got %s, want a,b,c", s)
+	}
+}
+
+func TestHTTPWriterDropsRejectedQueuedEntry(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		got      []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		b, _ := io.ReadAll(r.Body)
+		switch requests {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			got = append(got, string(b))
+		}
+	}))
+	defer ts.Close()
+	SetErrorHandler(func(error) {})
+	defer SetErrorHandler(nil)
+
+	h := NewHTTPWriter(ts.URL, nil)
+	h.Write([]byte("a"))
+	time.Sleep(time.Millisecond)
+	if _, err := h.Write([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if s := strings.Join(got, ","); s != "b" || h.Queued() != 0 {
+		t.Errorf("collector got %q with %d queued, want b and none", s, h.Queued())
+	}
+}
+
+func TestHTTPWriterTimeout(t *testing.T) {
+	if c := NewHTTPWriter("http://127.0.0.1/", nil).client; c == http.DefaultClient || c.Timeout != DefaultHTTPTimeout {
+		t.Errorf("default client timeout = %v, want %v", c.Timeout, DefaultHTTPTimeout)
+	}
+
+	hang := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer ts.Close()
+	defer close(hang)
+	SetErrorHandler(func(error) {})
+	defer SetErrorHandler(nil)
+
+	h := NewHTTPWriter(ts.URL, nil)
+	h.SetClient(&http.Client{Timeout: 50 * time.Millisecond})
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.Write([]byte("a"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Write to a hung collector succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write to a hung collector did not time out")
+	}
+}
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"crypto/rand"
+	"sync/atomic"
+	"time"
+)
+
+// EntryIDKey is the field key for entry IDs, see SetEntryIDs.
+const EntryIDKey = "id"
+
+// idGenerator holds the func() string set with SetIDGenerator.
+var idGenerator atomic.Value
+
+// SetIDGenerator sets the function that makes request IDs, see
+// WithRequestID, and entry IDs, see SetEntryIDs, such as one returning
+// UUIDv7s or snowflake IDs. fn must be safe for concurrent use. A nil
+// fn restores the default, ULID.
+func SetIDGenerator(fn func() string) {
+	if fn == nil {
+		fn = ULID
+	}
+	idGenerator.Store(fn)
+}
+
+// NewID returns an ID from the generator set with SetIDGenerator.
+func NewID() string {
+	if fn, ok := idGenerator.Load().(func() string); ok {
+		return fn()
+	}
+	return ULID()
+}
+
+// crockford is the alphabet of ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID returns a new ULID: 26 characters holding the time in
+// milliseconds and 80 random bits, so IDs sort by the time they were
+// made. IDs made in the same millisecond are in no particular order.
+func ULID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	rand.Read(b[6:])
+	// 128 bits encode to 26 characters of 5 bits, the first holding
+	// only the top 3.
+	var s [26]byte
+	var acc uint32
+	bits := 2 // pad the front so the 130 bits split into 26 characters
+	j := 0
+	for _, c := range b {
+		acc = acc<<8 | uint32(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			s[j] = crockford[(acc>>uint(bits))&31]
+			j++
+		}
+	}
+	return string(s[:])
+}
+
+var entryIDsEnabled int32
+
+// SetEntryIDs enables or disables adding an id field, from the ID
+// generator, to every entry, so entries can be referred to one by one
+// after they are shipped. The id field replaces a field of the same
+// name from the logger or the logging call; see Logger.With.
+func SetEntryIDs(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&entryIDsEnabled, v)
+}
+
+func addEntryID(e *entry) {
+	if atomic.LoadInt32(&entryIDsEnabled) == 0 {
+		return
+	}
+	fs := make([]Field, 0, len(e.fields)+1)
+	e.fields = append(append(fs, e.fields...), Str(EntryIDKey, NewID()))
+}
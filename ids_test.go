@@ -0,0 +1,42 @@
+package log
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestEntryIDOverridesField(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	n := 0
+	SetIDGenerator(func() string {
+		n++
+		return "id" + strconv.Itoa(n)
+	})
+	t.Cleanup(func() { SetIDGenerator(nil) })
+	SetEntryIDs(true)
+	t.Cleanup(func() { SetEntryIDs(false) })
+
+	l := NewLogger("ids", false).With(Str(EntryIDKey, "mine"))
+	l.Info("one")
+	l.Log(InfoLevel, "two", Str(EntryIDKey, "call"))
+	AddHook(func(h *HookEntry) { h.AddField(EntryIDKey, "hook") })
+	t.Cleanup(ClearHooks)
+	l.Info("three")
+
+	want := "ids     |  one id=id1\nids     |  two id=id2\nids     |  three id=hook\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestULID(t *testing.T) {
+	a, b := ULID(), ULID()
+	if len(a) != 26 || a == b {
+		t.Errorf("ULID() = %q, %q, want two distinct 26 character IDs", a, b)
+	}
+	if a > b && a[:10] != b[:10] {
+		t.Errorf("ULIDs %q and %q are out of time order", a, b)
+	}
+}
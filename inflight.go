@@ -0,0 +1,48 @@
+package log
+
+import (
+	"net/http"
+	"runtime"
+	"sync/atomic"
+)
+
+// InFlightHandler returns a handler that counts the requests being
+// served by h and logs a warning when the count reaches threshold, with
+// the goroutine count, as an early sign that the server is saturated.
+// It warns again only after the count has fallen below half the
+// threshold, or to zero for thresholds under 2, which is logged at the
+// info level. Both go through the logger's level, like any entry.
+//
+// If the logger is nil, the default "main" logger is used. A threshold
+// of zero or less turns the warnings off.
+func InFlightHandler(h http.Handler, logger *Logger, threshold int) http.Handler {
+	if logger == nil {
+		logger = defaultLogger
+	}
+	var (
+		n      atomic.Int64
+		warned atomic.Bool
+		limit  = int64(threshold)
+		rearm  = max(limit/2, 1)
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cur := n.Add(1)
+		defer func() {
+			if cur := n.Add(-1); limit > 0 && cur < rearm && warned.CompareAndSwap(true, false) {
+				logger.Log(InfoLevel, "in-flight requests back below threshold", inFlightFields(cur, limit)...)
+			}
+		}()
+		if limit > 0 && cur >= limit && warned.CompareAndSwap(false, true) {
+			logger.Log(WarnLevel, "in-flight requests reached threshold", inFlightFields(cur, limit)...)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func inFlightFields(n, threshold int64) []Field {
+	return []Field{
+		Int64("in_flight", n),
+		Int64("threshold", threshold),
+		Int("goroutines", runtime.NumGoroutine()),
+	}
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// inFlightServer returns a handler whose requests block until release
+// is called, and a function to start one in the background.
+func inFlightServer(t *testing.T, threshold int) (start func(), release func(), wait func()) {
+	var (
+		wg      sync.WaitGroup
+		entered = make(chan struct{})
+		gate    = make(chan struct{})
+	)
+	h := InFlightHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-gate
+	}), NewLogger("http", false), threshold)
+	start = func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		}()
+		<-entered
+	}
+	release = func() { gate <- struct{}{} }
+	wait = wg.Wait
+	t.Cleanup(func() {
+		close(gate)
+		wg.Wait()
+	})
+	return start, release, wait
+}
+
+func TestInFlightHandlerRearms(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		threshold int
+		// requests started before each release, per round.
+		load int
+	}{
+		{"threshold 1", 1, 1},
+		{"threshold 4", 4, 4},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var buf syncBuffer
+			SetOutput(&buf)
+			t.Cleanup(ResetOutput)
+			start, release, wait := inFlightServer(t, c.threshold)
+			for round := 0; round < 2; round++ {
+				for i := 0; i < c.load; i++ {
+					start()
+				}
+				for i := 0; i < c.load; i++ {
+					release()
+				}
+				wait()
+			}
+			out := buf.String()
+			if n := strings.Count(out, "reached threshold"); n != 2 {
+				t.Errorf("warned %d times over two rounds, want 2: %q", n, out)
+			}
+			if n := strings.Count(out, "back below threshold"); n != 2 {
+				t.Errorf("logged %d recoveries over two rounds, want 2: %q", n, out)
+			}
+		})
+	}
+}
+
+func TestInFlightHandlerBelowThreshold(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	start, release, wait := inFlightServer(t, 3)
+	start()
+	start()
+	release()
+	release()
+	wait()
+	if out := buf.String(); out != "" {
+		t.Errorf("logged %q below the threshold", out)
+	}
+}
+
+func TestInFlightHandlerLevel(t *testing.T) {
+	keepSettings(t)
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	SetLevel(ErrorLevel)
+	start, release, wait := inFlightServer(t, 1)
+	start()
+	release()
+	wait()
+	if out := buf.String(); out != "" {
+		t.Errorf("logged %q at the error level", out)
+	}
+}
@@ -0,0 +1,63 @@
+package log
+
+import "fmt"
+
+// Lazy returns a field whose value is computed by fn only if the entry
+// is logged, after the level and sampling checks, so expensive values
+// cost nothing for entries that are dropped:
+//
+//	logger.Log(log.DebugLevel, "request", log.Lazy("body", func() interface{} {
+//		return dump(req)
+//	}))
+//
+// fn is called once per entry. Lazy fields added with With are called
+// for every entry of the logger.
+func Lazy(key string, fn func() interface{}) Field {
+	return Field{key: key, kind: lazyKind, value: fn}
+}
+
+// resolveLazy replaces the lazy fields of e with their values, and
+// provider fields with the fields they return. The fields are copied
+// first, as they may be shared with the logger.
+func resolveLazy(e *entry) {
+	i := 0
+	for i < len(e.fields) && e.fields[i].kind != lazyKind && e.fields[i].kind != providerKind {
+		i++
+	}
+	if i == len(e.fields) {
+		return
+	}
+	fs := make([]Field, i, len(e.fields))
+	copy(fs, e.fields)
+	for _, f := range e.fields[i:] {
+		switch f.kind {
+		case lazyKind:
+			fs = append(fs, Any(f.key, callLazy(f.value.(func() interface{}))))
+		case providerKind:
+			fs = append(fs, sortedFields(callProvider(f.value.(func() Fields)))...)
+		default:
+			fs = append(fs, f)
+		}
+	}
+	e.fields = fs
+}
+
+// callLazy calls fn, rendering a panic the way fmt does.
+func callLazy(fn func() interface{}) (v interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			v = fmt.Sprintf("%%!v(PANIC=Lazy: %v)", r)
+		}
+	}()
+	return fn()
+}
+
+// callProvider calls fn, logging a panic as a field.
+func callProvider(fn func() Fields) (fs Fields) {
+	defer func() {
+		if r := recover(); r != nil {
+			fs = Fields{"provider_panic": fmt.Sprint(r)}
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// LegacyText is an Encoder for the plain column format as scrapers
+// parse it today, for use with SetEncoder or AddOutputEncoder:
+//
+//	prefix  |  message key=value
+//	LVL  |  prefix  |  caller  |  message key=value
+//
+// The level column is shown for entries from loggers with debug
+// enabled and the caller column whenever the entry has a caller.
+// Prefixes are cut to 6 characters and padded, and callers padded to
+// 22.
+// Fatal entries are the DIE marker and fields on one line and the
+// error on the next.
+//
+// Unlike FormatText, which may gain color, symbols or other changes,
+// the layout of LegacyText is kept as is. Entries come out byte for
+// byte as the package printed them before it had levels other than
+// debug and info, fields or other formats. So, unlike FormatText,
+// messages, prefixes and callers are written as they are, and a message
+// with newlines spans several lines. Field keys and values, which the
+// old format did not have, are escaped as in FormatText. Warn and
+// error entries use WRN and ERR in the level column.
+var LegacyText Encoder = builtinEncoder{legacyTextEncoder{}}
+
+// legacyTextEncoder is a frozen copy of the uncolored text format.
+// Changes to the text format go in textEncoder, not here.
+type legacyTextEncoder struct{}
+
+func (legacyTextEncoder) encode(b *bytes.Buffer, e *entry) {
+	if e.level == FatalLevel {
+		b.WriteString("DIE")
+		writeTextFields(b, e.fields)
+		b.WriteByte('\n')
+		b.WriteString(e.message)
+		b.WriteByte('\n')
+		return
+	}
+	if e.debug {
+		lvl, ok := legacyLevels[e.level]
+		if !ok {
+			lvl = "???"
+		}
+		b.WriteString(lvl)
+		b.WriteString("  |  ")
+	}
+	writeRawPadded(b, cut(e.prefix, 6), 6)
+	b.WriteString("  |  ")
+	if e.caller != "" {
+		writeRawPadded(b, e.caller, 22)
+		b.WriteString("  |  ")
+	}
+	for range e.groups {
+		b.WriteString("  ")
+	}
+	b.WriteString(e.message)
+	writeTextFields(b, e.fields)
+	b.WriteByte('\n')
+}
+
+// writeRawPadded writes s unescaped and left aligned in n columns, as
+// "%-*s" does.
+func writeRawPadded(b *bytes.Buffer, s string, n int) {
+	b.WriteString(s)
+	for i := utf8.RuneCountInString(s); i < n; i++ {
+		b.WriteByte(' ')
+	}
+}
+
+var legacyLevels = map[Level]string{
+	DebugLevel: "DBG",
+	InfoLevel:  "NFO",
+	WarnLevel:  "WRN",
+	ErrorLevel: "ERR",
+}
+
+func cut(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
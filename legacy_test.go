@@ -0,0 +1,118 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+
+	log "github.com/dangersalad/go-log"
+)
+
+// The want strings below are what the package printed before the
+// encoder refactor, for the same calls. Downstream scrapers parse
+// this format, so LegacyText must keep producing it byte for byte.
+
+// captureLegacy returns the output of fn logged with LegacyText.
+func captureLegacy(t *testing.T, fn func()) string {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetEncoder(log.LegacyText)
+	defer func() {
+		log.SetFormat(log.FormatText)
+		log.ResetOutput()
+	}()
+	fn()
+	return buf.String()
+}
+
+func TestLegacyTextLoggers(t *testing.T) {
+	db := log.NewLogger("database", false)
+	api := log.NewLogger("api", false)
+	for _, c := range []struct {
+		name string
+		log  func()
+		want string
+	}{
+		{"prefix cut", func() { db.Info("connected") }, "databa  |  connected\n"},
+		{"prefix padded", func() { api.Info("short") }, "api     |  short\n"},
+		{"multi-arg Println", func() { db.Println("hello", "world", 42) }, "databa  |  hello world 42\n"},
+		{"package Println", func() { log.Println("a", 1, true) }, "main    |  a 1 true\n"},
+		{"Printf newline", func() { db.Printf("took %dms\n", 12) }, "databa  |  took 12ms\n"},
+		{"Infof", func() { db.Infof("plain %s", "format") }, "databa  |  plain format\n"},
+		{"raw newlines", func() { db.Infof("line one\nline two") }, "databa  |  line one\nline two\n"},
+		{"debug gated", func() { db.Debug("hidden") }, ""},
+		{"debugf gated", func() { db.Debugf("hidden %d", 1) }, ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := captureLegacy(t, c.log); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestLegacyTextColumns(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		entry log.Entry
+		want  string
+	}{
+		{
+			"debug entry",
+			log.Entry{Level: log.DebugLevel, Prefix: "worker", Caller: "base/gen/main.go:21", Message: "debug 1", Debug: true},
+			"DBG  |  worker  |  base/gen/main.go:21     |  debug 1\n",
+		},
+		{
+			"info from debug logger",
+			log.Entry{Level: log.InfoLevel, Prefix: "worker", Caller: "base/gen/main.go:22", Message: "info args", Debug: true},
+			"NFO  |  worker  |  base/gen/main.go:22     |  info args\n",
+		},
+		{
+			"long caller",
+			log.Entry{Level: log.InfoLevel, Prefix: "db", Caller: "pkg/store/postgres.go:1042", Message: "slow", Debug: true},
+			"NFO  |  db      |  pkg/store/postgres.go:1042  |  slow\n",
+		},
+		{
+			"long prefix",
+			log.Entry{Level: log.InfoLevel, Prefix: "scheduler", Message: "tick"},
+			"schedu  |  tick\n",
+		},
+		{
+			"fatal",
+			log.Entry{Level: log.FatalLevel, Prefix: "main", Message: "boom"},
+			"DIE\nboom\n",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var b bytes.Buffer
+			log.LegacyText.Encode(&b, &c.entry)
+			if got := b.String(); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+const legacyDieEnv = "LOG_TEST_LEGACY_DIE"
+
+func TestLegacyTextDie(t *testing.T) {
+	if os.Getenv(legacyDieEnv) != "" {
+		log.SetEncoder(log.LegacyText)
+		log.Die(errors.New("boom"), 3)
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLegacyTextDie$")
+	cmd.Env = append(os.Environ(), legacyDieEnv+"=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) || ee.ExitCode() != 3 {
+		t.Fatalf("Die exited with %v, want exit status 3", err)
+	}
+	if got, want := stderr.String(), "DIE\nboom\n"; got != want {
+		t.Errorf("stderr = %q, want %q", got, want)
+	}
+}
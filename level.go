@@ -0,0 +1,136 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the severity of an entry.
+type Level int32
+
+const (
+	// DebugLevel entries are only logged when debug is enabled.
+	DebugLevel Level = iota
+	// InfoLevel is the default level.
+	InfoLevel
+	// WarnLevel is for conditions that should be looked at.
+	WarnLevel
+	// ErrorLevel is for failures.
+	ErrorLevel
+	// FatalLevel is used by Die and is always logged.
+	FatalLevel
+)
+
+// levels holds the minimum level in the low byte and the package
+// debug setting in debugBit, so both can be replaced at once.
+var levels = int32(DebugLevel)
+
+const debugBit = 1 << 8
+
+// String returns the lower case name of the level.
+func (lvl Level) String() string {
+	switch lvl {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case FatalLevel:
+		return "fatal"
+	}
+	return fmt.Sprintf("level(%d)", int32(lvl))
+}
+
+func (lvl Level) prefix() string {
+	switch lvl {
+	case DebugLevel:
+		return debugPrefix
+	case InfoLevel:
+		return infoPrefix
+	case WarnLevel:
+		return "WRN"
+	case ErrorLevel:
+		return "ERR"
+	case FatalLevel:
+		return "DIE"
+	}
+	return "???"
+}
+
+// ParseLevel parses a level name as returned by Level.String. "warning"
+// is accepted for WarnLevel.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	}
+	return InfoLevel, fmt.Errorf("unknown log level %q", s)
+}
+
+// SetLevel sets the minimum level logged by all loggers. Debug
+// entries additionally require debug to be enabled on the logger, so
+// the default of DebugLevel leaves them up to each logger.
+//
+// The initial level is taken from LOG_LEVEL, or WarnLevel if
+// LOG_QUIET is non empty.
+func SetLevel(lvl Level) {
+	updateLevels(func(v int32) int32 {
+		return v&debugBit | int32(lvl)&0xff
+	})
+}
+
+// GetLevel returns the minimum level logged by all loggers.
+func GetLevel() Level {
+	return Level(atomic.LoadInt32(&levels) & 0xff)
+}
+
+func updateLevels(fn func(int32) int32) {
+	for {
+		v := atomic.LoadInt32(&levels)
+		if atomic.CompareAndSwapInt32(&levels, v, fn(v)) {
+			return
+		}
+	}
+}
+
+func storeLevels(debug bool, lvl Level) {
+	v := int32(lvl) & 0xff
+	if debug {
+		v |= debugBit
+	}
+	atomic.StoreInt32(&levels, v)
+}
+
+// SetQuiet suppresses info output while keeping warnings and errors,
+// for command line tools offering a --quiet flag. Turning it off goes
+// back to DebugLevel.
+func SetQuiet(quiet bool) {
+	if quiet {
+		SetLevel(WarnLevel)
+	} else {
+		SetLevel(DebugLevel)
+	}
+}
+
+func envLevel() (Level, bool) {
+	if lvl, err := ParseLevel(os.Getenv("LOG_LEVEL")); err == nil {
+		return lvl, true
+	}
+	if os.Getenv("LOG_QUIET") != "" {
+		return WarnLevel, true
+	}
+	return DebugLevel, false
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+)
+
+// FormatLine is a single line text format for reading logs through
+// tools like kubectl logs and k9s, which wrap the column format badly:
+//
+//	2024-01-02T03:04:05.678Z INFO  main main.go:12 message key=value
+//
+// The level comes first, after the timestamp, and there are no column
+// separators or padding. Die entries are on one line too.
+const FormatLine Format = "line"
+
+// lineEncoder renders FormatLine.
+type lineEncoder struct {
+	// levels are the rendered level names, padded and colored,
+	// indexed by level.
+	levels [FatalLevel + 1]string
+}
+
+func newLineEncoder(color bool) *lineEncoder {
+	enc := &lineEncoder{}
+	for lvl := DebugLevel; lvl <= FatalLevel; lvl++ {
+		name := strings.ToUpper(lvl.String())
+		if lvl == FatalLevel {
+			name = "DIE"
+		}
+		name += strings.Repeat(" ", 5-len(name))
+		if color {
+			name = levelColors[lvl] + name + "\x1b[0m"
+		}
+		enc.levels[lvl] = name
+	}
+	return enc
+}
+
+func (l *lineEncoder) encode(b *bytes.Buffer, e *entry) {
+	b.Write(e.time.UTC().AppendFormat(b.AvailableBuffer(), "2006-01-02T15:04:05.000Z07:00"))
+	b.WriteByte(' ')
+	if e.level >= DebugLevel && e.level <= FatalLevel {
+		b.WriteString(l.levels[e.level])
+	} else {
+		b.WriteString(e.level.prefix())
+	}
+	b.WriteByte(' ')
+	writeTextString(b, e.prefix)
+	if e.caller != "" {
+		b.WriteByte(' ')
+		b.WriteString(e.caller)
+	}
+	b.WriteByte(' ')
+	for range e.groups {
+		b.WriteString(groupIndent)
+	}
+	writeTextString(b, e.message)
+	writeTextFields(b, e.fields)
+	b.WriteByte('\n')
+}
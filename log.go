@@ -4,30 +4,52 @@
 // rationale.
 //
 // Debug logging is controlled via environment variables. Set
-// DEPLOY_ENV to "dev" or "development", or set LOG_DEBUG to a non
-// empty value to enable the debug log.
+// DEPLOY_ENV (or APP_ENV or GO_ENV) to "dev" or "development", or set
+// LOG_DEBUG to a non empty value to enable the debug log. See
+// SetEnvDetector for other conventions. LOG_LEVEL sets the minimum
+// level logged (debug, info, warn or error) and LOG_QUIET, if non
+// empty, suppresses info output. LOG_FORMAT sets the format, and
+// LOG_FIELDS, such as "region=us-east-1,team=payments", adds fields to
+// every entry. Invalid
+// values are ignored with a warning, or stop the process at startup if
+// LOG_STRICT is set. See CheckEnv.
+//
+// Output goes to stdout. See SetOutput and SetFormat to change that.
+// DEPLOY_ENV also selects a Profile of defaults: colored text with
+// callers for development, single line text for "kube" and JSON for
+// "prod" or "production". Without an environment, LOG_PROFILE names the
+// profile, and if that is not set either, the development profile is
+// used when stdout is a terminal and production otherwise, so local
+// runs are readable and containers get JSON. LOG_PROFILE=none keeps the
+// plain column format.
 package log // import "github.com/dangersalad/go-log"
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var (
-	debugPrefix   = "DBG"
-	infoPrefix    = "NFO"
-	defaultLogger = NewLogger("main", true)
+const (
+	debugPrefix = "DBG"
+	infoPrefix  = "NFO"
 )
 
+var defaultLogger = NewLogger("main", true)
+
 // SetDefaultName changes the name of the package level logger.
 func SetDefaultName(n string) {
 	if len(n) > prefixLimit {
-		defaultLogger.prefix = n[0:prefixLimit]
-	} else {
-		defaultLogger.prefix = n
+		n = n[0:prefixLimit]
 	}
+	defaultLogger.setPrefix(n)
 }
 
 // Debug logs a debug message
@@ -60,6 +82,36 @@ func Infof(f string, a ...interface{}) {
 	defaultLogger.Infof(f, a...)
 }
 
+// Warn logs a warning
+func Warn(a ...interface{}) {
+	defaultLogger.Warn(a...)
+}
+
+// Warnln logs a warning
+func Warnln(a ...interface{}) {
+	defaultLogger.Warn(a...)
+}
+
+// Warnf logs a formatted warning
+func Warnf(f string, a ...interface{}) {
+	defaultLogger.Warnf(f, a...)
+}
+
+// Error logs an error message
+func Error(a ...interface{}) {
+	defaultLogger.Error(a...)
+}
+
+// Errorln logs an error message
+func Errorln(a ...interface{}) {
+	defaultLogger.Error(a...)
+}
+
+// Errorf logs a formatted error message
+func Errorf(f string, a ...interface{}) {
+	defaultLogger.Errorf(f, a...)
+}
+
 // Print is an alias for Info
 func Print(a ...interface{}) {
 	Info(a...)
@@ -78,6 +130,9 @@ func Printf(f string, a ...interface{}) {
 // Die will log out an error with "%+v" and exit the process with an
 // optional code.
 //
+// If no code is given and the error, or any error it wraps,
+// implements ExitCoder, that code is used. Otherwise the code is 1.
+//
 // Only available at the package level. This is meant to be used with
 // github.com/pkg/errors and only at the top level of a process to
 // handle errors that bubble up.
@@ -85,10 +140,64 @@ func Die(err error, code ...int) {
 	defaultLogger.die(err, code...)
 }
 
+// DieIf calls Die if err is not nil.
+func DieIf(err error, code ...int) {
+	if err != nil {
+		defaultLogger.die(err, code...)
+	}
+}
+
+// Must returns v, or calls Die if err is not nil.
+//
+//	cfg := log.Must(loadConfig())
+func Must[T any](v T, err error) T {
+	if err != nil {
+		defaultLogger.die(err)
+	}
+	return v
+}
+
+// ExitCoder is implemented by errors that carry a process exit code.
+type ExitCoder interface {
+	ExitCode() int
+}
+
 // Logger is a logger with a prefix
 type Logger struct {
-	prefix       string
+	prefix string
+	// prefixCol is the prefix padded to the column width followed by
+	// the separator, as rendered by the text format.
+	prefixCol string
+	// debugEnabled is false if debug is always disabled, otherwise
+	// the package debug setting decides.
 	debugEnabled bool
+	fields       []Field
+	// fatalFields are only added to Die entries.
+	fatalFields []Field
+	// overrides are the logger's own, falling back to those of the
+	// logger it was derived from.
+	overrides *overrides
+	// seq numbers entries, if set by WithSequence.
+	seq *atomic.Uint64
+}
+
+// overrides are per logger settings that take precedence over the
+// package settings. Unset values fall back to the parent's, so a
+// derived logger follows the logger it came from without changing it.
+type overrides struct {
+	parent *overrides
+	// debug is 0 to follow the package setting, 1 for on, 2 for off.
+	debug int32
+	// level is the minimum level plus one, or 0 to follow the package
+	// setting.
+	level int32
+	// debugUntil is the UnixNano time until which debug is on, set
+	// by TempDebug.
+	debugUntil int64
+	// groups is the stack of open Group titles. It is replaced, not
+	// modified, so entries can keep a reference.
+	groupMu sync.Mutex
+	groups  []string
 }
 
 const prefixLimit = 6
@@ -99,18 +208,42 @@ const callerLimit = 22
 // always disabled. If `true`, it will follow the environment
 // variables.
 func NewLogger(prefix string, debugEnabled bool) *Logger {
-	d := false
-	if debugEnabled {
-		d = checkDebugEnabled()
-	}
 	// limit prefix
 	if len(prefix) > prefixLimit {
 		prefix = prefix[0:prefixLimit]
 	}
-	return &Logger{
-		prefix:       prefix,
-		debugEnabled: d,
+	l := &Logger{
+		debugEnabled: debugEnabled,
+		overrides:    &overrides{},
 	}
+	l.setPrefix(prefix)
+	return l
+}
+
+// withPrefix returns a copy of the logger with a different prefix.
+func (l *Logger) withPrefix(prefix string) *Logger {
+	if len(prefix) > prefixLimit {
+		prefix = prefix[0:prefixLimit]
+	}
+	c := l.derive()
+	c.setPrefix(prefix)
+	return c
+}
+
+// derive returns a copy of the logger with its own overrides, which
+// fall back to the logger's.
+func (l *Logger) derive() *Logger {
+	c := *l
+	c.overrides = &overrides{parent: l.ov()}
+	return &c
+}
+
+func (l *Logger) setPrefix(prefix string) {
+	var b bytes.Buffer
+	writePadded(&b, prefix, prefixLimit)
+	b.WriteString(columnSep)
+	l.prefix = prefix
+	l.prefixCol = b.String()
 }
 
 // Debug logs a debug message with the logger's prefix
@@ -143,6 +276,36 @@ func (l *Logger) Infof(f string, a ...interface{}) {
 	l.infof(f, a...)
 }
 
+// Warn logs a warning with the logger's prefix
+func (l *Logger) Warn(a ...interface{}) {
+	l.warn(a...)
+}
+
+// Warnln logs a warning with the logger's prefix
+func (l *Logger) Warnln(a ...interface{}) {
+	l.warn(a...)
+}
+
+// Warnf logs a formatted warning with the logger's prefix
+func (l *Logger) Warnf(f string, a ...interface{}) {
+	l.warnf(f, a...)
+}
+
+// Error logs an error message with the logger's prefix
+func (l *Logger) Error(a ...interface{}) {
+	l.error(a...)
+}
+
+// Errorln logs an error message with the logger's prefix
+func (l *Logger) Errorln(a ...interface{}) {
+	l.error(a...)
+}
+
+// Errorf logs a formatted error message with the logger's prefix
+func (l *Logger) Errorf(f string, a ...interface{}) {
+	l.errorf(f, a...)
+}
+
 // Print is an alias for Info
 func (l *Logger) Print(a ...interface{}) {
 	l.Info(a...)
@@ -159,77 +322,319 @@ func (l *Logger) Printf(f string, a ...interface{}) {
 }
 
 func (l *Logger) debug(a ...interface{}) {
-	if !l.debugEnabled {
+	if !l.isDebug() {
+		if recentWanted(DebugLevel) {
+			l.keepRecent(DebugLevel, sprintln(a), nil)
+		}
 		return
 	}
-	l.output(debugPrefix, a...)
+	l.output(DebugLevel, a...)
 }
 
 func (l *Logger) debugf(f string, a ...interface{}) {
-	if !l.debugEnabled {
+	if !l.isDebug() {
+		if recentWanted(DebugLevel) {
+			l.keepRecent(DebugLevel, sprintf(f, a), nil)
+		}
 		return
 	}
-	l.outputf(debugPrefix, f, a...)
+	l.outputf(DebugLevel, f, a...)
 }
 
 func (l *Logger) info(a ...interface{}) {
-	l.output(infoPrefix, a...)
+	l.output(InfoLevel, a...)
 }
 
 func (l *Logger) infof(f string, a ...interface{}) {
-	l.outputf(infoPrefix, f, a...)
+	l.outputf(InfoLevel, f, a...)
+}
+
+func (l *Logger) warn(a ...interface{}) {
+	l.output(WarnLevel, a...)
+}
+
+func (l *Logger) warnf(f string, a ...interface{}) {
+	l.outputf(WarnLevel, f, a...)
 }
 
-func (l *Logger) output(levelPrefix string, a ...interface{}) {
-	if l.debugEnabled {
-		a = append([]interface{}{fmt.Sprintf("%-22s  | ", getCaller())}, a...)
+func (l *Logger) error(a ...interface{}) {
+	l.output(ErrorLevel, a...)
+}
+
+func (l *Logger) errorf(f string, a ...interface{}) {
+	l.outputf(ErrorLevel, f, a...)
+}
+
+// enabled reports whether an entry at lvl would be logged.
+func (l *Logger) enabled(lvl Level) bool {
+	if lvl == DebugLevel && !l.isDebug() {
+		return false
 	}
-	a = append([]interface{}{fmt.Sprintf("%-6s  | ", l.prefix)}, a...)
-	if l.debugEnabled {
-		a = append([]interface{}{fmt.Sprintf("%s  | ", levelPrefix)}, a...)
+	return l.levelEnabled(lvl)
+}
+
+// Log logs msg at lvl with the fields added to the entry, without
+// formatting. Use Die for fatal errors.
+//
+//	log.Log(log.InfoLevel, "request done", log.Int("status", code), log.Dur("took", d))
+func Log(lvl Level, msg string, fs ...Field) {
+	defaultLogger.Log(lvl, msg, fs...)
+}
+
+// Log logs msg at lvl with the fields added to the entry, without
+// formatting. Unlike WithFields, the typed fields are not boxed, so this
+// is the cheapest way to log structured data on hot paths. Use Die for
+// fatal errors.
+func (l *Logger) Log(lvl Level, msg string, fs ...Field) {
+	if !l.enabled(lvl) {
+		if recentWanted(lvl) {
+			l.keepRecent(lvl, msg, fs)
+		}
+		return
+	}
+	l.writeFields(lvl, msg, fs)
+}
+
+// LogAt logs msg at lvl with the default logger, timestamped t. See
+// Logger.LogAt.
+func LogAt(t time.Time, lvl Level, msg string, fs ...Field) {
+	defaultLogger.LogAt(t, lvl, msg, fs...)
+}
+
+// LogAt is Log with the entry timestamped t instead of the current
+// time, for entries forwarded or replayed from other systems that
+// should keep their original times. A zero t means the current time.
+func (l *Logger) LogAt(t time.Time, lvl Level, msg string, fs ...Field) {
+	if !l.enabled(lvl) {
+		if recentWanted(lvl) {
+			e := l.newEntry(lvl, msg, fs)
+			if !t.IsZero() {
+				e.time = t
+			}
+			keepRecentEntry(e)
+		}
+		return
+	}
+	e := l.newEntry(lvl, msg, fs)
+	if !t.IsZero() {
+		e.time = t
+	}
+	emit(e)
+	e.free()
+}
+
+// logf logs a formatted message at lvl, with the same debug gating as
+// Debugf for DebugLevel.
+func (l *Logger) logf(lvl Level, f string, a ...interface{}) {
+	if lvl == DebugLevel {
+		l.debugf(f, a...)
+		return
 	}
+	l.outputf(lvl, f, a...)
+}
 
-	fmt.Println(a...)
+func (l *Logger) output(lvl Level, a ...interface{}) {
+	if !l.levelEnabled(lvl) {
+		if recentWanted(lvl) {
+			l.keepRecent(lvl, sprintln(a), nil)
+		}
+		return
+	}
+	if formatChecking() {
+		l.checkArgs(a)
+	}
+	l.write(lvl, sprintln(a))
 }
 
-func (l *Logger) outputf(levelPrefix, f string, a ...interface{}) {
-	if l.debugEnabled {
-		f = fmt.Sprintf("%s  |  %-6s  |  %-22s  |  %s", levelPrefix, l.prefix, getCaller(), f)
-	} else {
-		f = fmt.Sprintf("%-6s  |  %s", l.prefix, f)
+func (l *Logger) outputf(lvl Level, f string, a ...interface{}) {
+	if !l.levelEnabled(lvl) {
+		if recentWanted(lvl) {
+			l.keepRecent(lvl, sprintf(f, a), nil)
+		}
+		return
+	}
+	msg := fmt.Sprintf(f, a...)
+	if formatChecking() {
+		l.checkFormat(f, msg)
+	}
+	if len(f) > 0 && f[len(f)-1] == '\n' {
+		msg = msg[:len(msg)-1]
 	}
+	l.write(lvl, msg)
+}
+
+// sprintln formats a as Println does, without the newline.
+func sprintln(a []interface{}) string {
+	msg := fmt.Sprintln(a...)
+	return msg[:len(msg)-1]
+}
+
+// sprintf formats a as Printf does, without a trailing newline.
+func sprintf(f string, a []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintf(f, a...), "\n")
+}
+
+func (l *Logger) write(lvl Level, msg string) {
+	l.writeFields(lvl, msg, nil)
+}
+
+func (l *Logger) writeFields(lvl Level, msg string, fs []Field) {
+	e := l.newEntry(lvl, msg, fs)
+	emit(e)
+	e.free()
+}
 
-	if f[len(f)-1] != '\n' {
-		f += "\n"
+// logFrom is Log with site as the entry's caller, for entries logged
+// later on behalf of the code at site, such as by Watch.
+func (l *Logger) logFrom(site string, lvl Level, msg string, fs ...Field) {
+	if !l.enabled(lvl) {
+		return
+	}
+	e := l.newEntry(lvl, msg, fs)
+	if callerEnabled(e.debug) {
+		e.caller = site
+	}
+	emit(e)
+	e.free()
+}
+
+// keepRecent adds an entry that is not logged to the recent entries.
+func (l *Logger) keepRecent(lvl Level, msg string, fs []Field) {
+	keepRecentEntry(l.newEntry(lvl, msg, fs))
+}
+
+func keepRecentEntry(e *entry) {
+	resolveLazy(e)
+	e.fields = dedupFields(e.fields)
+	recent.add(e)
+	e.free()
+}
+
+func (l *Logger) newEntry(lvl Level, msg string, fs []Field) *entry {
+	debug := l.isDebug()
+	e := newEntry()
+	e.time = time.Now()
+	e.level = lvl
+	e.prefix = l.prefix
+	e.prefixCol = l.prefixCol
+	e.message = msg
+	e.fields = l.entryFields()
+	if len(e.fields) == 0 {
+		e.fields = fs
+	} else if len(fs) > 0 {
+		// Cap the logger's fields so appending can't write into them.
+		e.fields = append(e.fields[:len(e.fields):len(e.fields)], fs...)
 	}
-	fmt.Printf(f, a...)
+	e.groups = l.openGroups()
+	e.debug = debug
+	e.seq = l.seq
+	if callerEnabled(debug) {
+		e.caller = getCaller()
+	}
+	return e
 }
 
 func (l *Logger) die(err error, code ...int) {
-	fmt.Fprintf(os.Stderr, "DIE\n%+v\n", err)
-	c := 1
-	if len(code) > 0 {
-		c = code[0]
+	now := time.Now()
+	fs := l.dieFields()
+	if path := writeCrashReport(now, err); path != "" {
+		fs = append(fs[:len(fs):len(fs)], Str(CrashReportKey, path))
 	}
-	os.Exit(c)
+	emit(&entry{
+		time:    now,
+		level:   FatalLevel,
+		prefix:  l.prefix,
+		message: fmt.Sprintf("%+v", err),
+		fields:  fs,
+		seq:     l.seq,
+	})
+	os.Exit(exitCode(err, code...))
+}
+
+func (l *Logger) dieFields() []Field {
+	fs := l.entryFields()
+	if len(l.fatalFields) == 0 {
+		return fs
+	}
+	return append(fs[:len(fs):len(fs)], l.fatalFields...)
+}
+
+// withFatal returns a copy of the logger that adds fs to Die entries
+// only.
+func (l *Logger) withFatal(fs ...Field) *Logger {
+	c := l.derive()
+	c.fatalFields = make([]Field, 0, len(l.fatalFields)+len(fs))
+	c.fatalFields = append(append(c.fatalFields, l.fatalFields...), fs...)
+	return c
 }
 
-func getCaller(s ...int) string {
-	skip := 0
-	if len(s) == 1 {
-		skip = s[0]
+func exitCode(err error, code ...int) int {
+	if len(code) > 0 {
+		return code[0]
 	}
-	_, fullfile, line, ok := runtime.Caller(skip)
-	if !ok {
-		return ""
+	var ec ExitCoder
+	if errors.As(err, &ec) {
+		return ec.ExitCode()
 	}
+	return 1
+}
+
+// callerCache maps a program counter to the caller it resolves to, or
+// to "" if the frames at that pc are all logging code to skip.
+var callerCache sync.Map
+
+// getCaller returns the first caller outside of the logging code.
+// Resolving program counters to files is the expensive part, so it is
+// done once per call site and cached.
+func getCaller() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for _, pc := range pcs[:n] {
+		if c, ok := callerCache.Load(pc); ok {
+			if c != "" {
+				return c.(string)
+			}
+			continue
+		}
+		c := resolveCaller(pc)
+		callerCache.Store(pc, c)
+		if c != "" {
+			return c
+		}
+	}
+	return ""
+}
 
-	if strings.Index(fullfile, "log.go") >= 0 || strings.Index(fullfile, "logging.go") >= 0 {
-		return getCaller(skip + 2)
+// resolveCaller returns the normalized caller for pc, taking inlined
+// frames into account, or "" if it is logging or runtime code.
+func resolveCaller(pc uintptr) string {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		f, more := frames.Next()
+		if f.File != "" && !skipCallerFile(f.File) && !strings.HasPrefix(f.Function, "runtime.") {
+			return normalizeCaller(f.Line, f.File)
+		}
+		if !more {
+			return ""
+		}
 	}
+}
 
-	return normalizeCaller(line, fullfile)
+func skipCallerFile(fullfile string) bool {
+	return strings.Index(fullfile, "log.go") >= 0 || strings.Index(fullfile, "logging.go") >= 0 || inPackage(fullfile)
+}
 
+// pkgDir is the directory of this package's source, used to skip
+// frames from helpers outside log.go.
+var pkgDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return path.Dir(file) + "/"
+}()
+
+func inPackage(fullfile string) bool {
+	return strings.HasPrefix(fullfile, pkgDir) &&
+		!strings.HasSuffix(fullfile, "_test.go") &&
+		!strings.Contains(fullfile[len(pkgDir):], "/")
 }
 
 func normalizeCaller(line int, fullfile string, counts ...int) string {
@@ -238,6 +643,9 @@ func normalizeCaller(line int, fullfile string, counts ...int) string {
 		count = counts[0]
 	}
 	parts := strings.Split(fullfile, "/")
+	if count > len(parts) {
+		count = len(parts)
+	}
 	file := strings.Join(parts[len(parts)-count:], "/")
 
 	caller := fmt.Sprintf("%s:%d", file, line)
@@ -251,11 +659,116 @@ func normalizeCaller(line int, fullfile string, counts ...int) string {
 	return caller
 }
 
+// SetDebug forces debug output on or off for the logger, and loggers
+// derived from it, regardless of the package setting and environment.
+// This works even if the logger was created with debugEnabled false.
+// The logger it was derived from, if any, is not affected.
+func (l *Logger) SetDebug(enabled bool) {
+	v := int32(2)
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&l.ov().debug, v)
+}
+
+// SetLevel sets the minimum level for the logger, and loggers derived
+// from it, regardless of the package setting and environment. Like
+// SetDebug, it does not affect the logger it was derived from.
+func (l *Logger) SetLevel(lvl Level) {
+	atomic.StoreInt32(&l.ov().level, int32(lvl)+1)
+}
+
+// ResetOverrides makes the logger follow the logger it was derived
+// from, or the package settings, again after SetDebug or SetLevel.
+func (l *Logger) ResetOverrides() {
+	atomic.StoreInt32(&l.ov().debug, 0)
+	atomic.StoreInt32(&l.ov().level, 0)
+}
+
+// ov returns the logger's overrides, allocating them for a zero Logger.
+func (l *Logger) ov() *overrides {
+	if l.overrides == nil {
+		l.overrides = &overrides{}
+	}
+	return l.overrides
+}
+
+func (l *Logger) isDebug() bool {
+	if l.tempDebug() {
+		return true
+	}
+	switch l.ov().debugOverride() {
+	case 1:
+		return true
+	case 2:
+		return false
+	}
+	return l.debugEnabled && debugSetting()
+}
+
+// debugOverride returns the nearest debug override, or 0 if none is
+// set.
+func (ov *overrides) debugOverride() int32 {
+	for ; ov != nil; ov = ov.parent {
+		if v := atomic.LoadInt32(&ov.debug); v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// levelOverride returns the nearest level override, or 0 if none is
+// set.
+func (ov *overrides) levelOverride() int32 {
+	for ; ov != nil; ov = ov.parent {
+		if v := atomic.LoadInt32(&ov.level); v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// levelEnabled reports whether lvl passes the logger's minimum level.
+// Debug forced on for the logger passes the package level, but not a
+// level set on the logger.
+func (l *Logger) levelEnabled(lvl Level) bool {
+	if lvl == DebugLevel && l.tempDebug() {
+		return true
+	}
+	ov := l.ov()
+	if v := ov.levelOverride(); v > 0 {
+		return lvl >= Level(v-1)
+	}
+	if lvl == DebugLevel && ov.debugOverride() == 1 {
+		return true
+	}
+	return lvl >= GetLevel()
+}
+
+func debugSetting() bool {
+	return atomic.LoadInt32(&levels)&debugBit != 0
+}
+
+// SetDebug enables or disables debug output for all loggers that were
+// created with debugEnabled, overriding the environment variables.
+// Enabling it also lowers the minimum level to DebugLevel, as LOG_DEBUG
+// does, so it works under a profile with a higher level such as
+// Production. Disabling it leaves the level alone.
+func SetDebug(enabled bool) {
+	updateLevels(func(v int32) int32 {
+		if enabled {
+			return debugBit | int32(DebugLevel)
+		}
+		return v &^ debugBit
+	})
+}
+
 func checkDebugEnabled() bool {
-	deployEnv := os.Getenv("DEPLOY_ENV")
+	deployEnv := Environment()
 	return deployEnv == "dev" ||
 		deployEnv == "development" ||
 		deployEnv == "test" ||
 		deployEnv == "testing" ||
-		os.Getenv("LOG_DEBUG") != ""
+		os.Getenv("LOG_DEBUG") != "" ||
+		strings.EqualFold(os.Getenv("LOG_LEVEL"), "debug")
 }
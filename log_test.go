@@ -0,0 +1,173 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that is safe to read while entries are
+// logged from other goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// keepSettings restores the package settings a profile changes when
+// the test ends.
+func keepSettings(t *testing.T) {
+	lv := atomic.LoadInt32(&levels)
+	caller := atomic.LoadInt32(&callerMode)
+	fc := atomic.LoadInt32(&formatCheck)
+	sc := atomic.LoadInt32(&schemaCheck)
+	out.mu.Lock()
+	format, color, symbols := out.format, out.color, out.symbols
+	out.mu.Unlock()
+	t.Cleanup(func() {
+		atomic.StoreInt32(&levels, lv)
+		atomic.StoreInt32(&callerMode, caller)
+		atomic.StoreInt32(&formatCheck, fc)
+		atomic.StoreInt32(&schemaCheck, sc)
+		SetFormat(format)
+		SetColor(color)
+		SetSymbols(symbols)
+	})
+}
+
+func TestSetDebugUnderProduction(t *testing.T) {
+	keepSettings(t)
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+
+	SetProfile(Production)
+	l := NewLogger("prod", true)
+	l.Debug("hidden")
+	SetDebug(true)
+	if GetLevel() != DebugLevel {
+		t.Errorf("level after SetDebug(true) = %v, want debug", GetLevel())
+	}
+	l.Debug("shown")
+	SetDebug(false)
+	l.Debug("hidden again")
+	l.Info("info")
+
+	got := buf.String()
+	if !strings.Contains(got, `"msg":"shown"`) || strings.Contains(got, "hidden") {
+		t.Errorf("output = %q, want only the debug entry logged while debug was on", got)
+	}
+	if !strings.Contains(got, `"msg":"info"`) {
+		t.Errorf("output = %q, want the info entry", got)
+	}
+}
+
+func TestDerivedOverrides(t *testing.T) {
+	parent := NewLogger("parent", false)
+	child := parent.WithField("k", 1)
+	sibling := parent.withPrefix("sib")
+
+	child.SetDebug(true)
+	child.SetLevel(WarnLevel)
+	child.TempDebug(time.Minute)
+	end := child.Group("step")
+	defer end()
+
+	if parent.isDebug() || sibling.isDebug() {
+		t.Error("SetDebug on a derived logger turned on debug for its parent")
+	}
+	if !parent.levelEnabled(InfoLevel) || !sibling.levelEnabled(InfoLevel) {
+		t.Error("SetLevel on a derived logger changed its parent's level")
+	}
+	if g := parent.openGroups(); len(g) != 0 {
+		t.Errorf("parent groups = %q, want none", g)
+	}
+	if !child.isDebug() || child.levelEnabled(InfoLevel) {
+		t.Error("derived logger lost its own overrides")
+	}
+
+	parent.SetLevel(ErrorLevel)
+	other := NewLogger("other", false).withPrefix("x")
+	if !other.levelEnabled(WarnLevel) {
+		t.Error("unrelated logger followed another logger's level")
+	}
+	if sibling.levelEnabled(WarnLevel) {
+		t.Error("derived logger did not follow its parent's level")
+	}
+	sibling.SetLevel(DebugLevel)
+	sibling.ResetOverrides()
+	if sibling.levelEnabled(WarnLevel) {
+		t.Error("ResetOverrides did not fall back to the parent's level")
+	}
+
+	defer parent.Group("outer")()
+	if g := child.openGroups(); len(g) != 2 || g[0] != "outer" || g[1] != "step" {
+		t.Errorf("child groups = %q, want [outer step]", g)
+	}
+}
+
+func TestDebugfDisabledAllocs(t *testing.T) {
+	l := NewLogger("allocs", false)
+	n := testing.AllocsPerRun(100, func() {
+		l.Debugf("cache miss for %s after %d tries", "key", 3)
+	})
+	if n != 0 {
+		t.Errorf("disabled Debugf allocates %v times per call, want 0", n)
+	}
+}
+
+func benchLogger(b *testing.B, f Format) *Logger {
+	SetOutput(io.Discard)
+	SetFormat(f)
+	b.Cleanup(func() {
+		SetFormat(FormatText)
+		ResetOutput()
+	})
+	l := NewLogger("bench", false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	return l
+}
+
+func BenchmarkDebugfDisabled(b *testing.B) {
+	l := benchLogger(b, FormatText)
+	for i := 0; i < b.N; i++ {
+		l.Debugf("cache miss for %s after %d tries", "key", 3)
+	}
+}
+
+func BenchmarkInfof(b *testing.B) {
+	l := benchLogger(b, FormatText)
+	for i := 0; i < b.N; i++ {
+		l.Infof("cache miss for %s after %d tries", "key", 3)
+	}
+}
+
+func BenchmarkInfoJSON(b *testing.B) {
+	l := benchLogger(b, FormatJSON)
+	for i := 0; i < b.N; i++ {
+		l.Infof("cache miss for %s after %d tries", "key", 3)
+	}
+}
+
+func BenchmarkLogJSON(b *testing.B) {
+	l := benchLogger(b, FormatJSON)
+	for i := 0; i < b.N; i++ {
+		l.Log(InfoLevel, "cache miss", Str("key", "key"), Int("tries", 3))
+	}
+}
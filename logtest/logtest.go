@@ -0,0 +1,139 @@
+// Package logtest helps tests lock down the exact output of a flow by
+// comparing captured log output with a golden file, and the fields of
+// entries with RequireSchema.
+//
+// Volatile parts of the output, timestamps, durations and ports, are
+// replaced with placeholders before comparing. Set LOGTEST_UPDATE=1 to
+// write the golden files instead.
+package logtest
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	log "github.com/dangersalad/go-log"
+)
+
+// UpdateEnv is the environment variable that rewrites golden files
+// with the current output when set to 1.
+const UpdateEnv = "LOGTEST_UPDATE"
+
+// Buffer collects log output. It is safe for concurrent use.
+type Buffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write appends p to the buffer.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Bytes returns a copy of the output collected so far.
+func (b *Buffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+// String returns the output collected so far.
+func (b *Buffer) String() string {
+	return string(b.Bytes())
+}
+
+// Capture sends all log output to a new Buffer until the test ends,
+// when the default output is restored with log.ResetOutput.
+func Capture(tb testing.TB) *Buffer {
+	tb.Helper()
+	b := new(Buffer)
+	log.SetOutput(b)
+	tb.Cleanup(log.ResetOutput)
+	return b
+}
+
+var volatile = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?(Z|[+-]\d\d:\d\d)`), "<time>"},
+	{regexp.MustCompile(`\b(\d+(\.\d+)?(ns|µs|us|ms|s|m|h))+\b`), "<duration>"},
+	{regexp.MustCompile(`(\d|\]|localhost):\d{2,5}\b`), "$1:<port>"},
+}
+
+// Normalize replaces timestamps, durations and ports in b with
+// placeholders.
+func Normalize(b []byte) []byte {
+	for _, v := range volatile {
+		b = v.re.ReplaceAll(b, []byte(v.repl))
+	}
+	return b
+}
+
+// AssertGolden fails the test unless the normalized output matches the
+// golden file at path. With LOGTEST_UPDATE=1 the file is written
+// instead, creating its directory if needed.
+func AssertGolden(tb testing.TB, path string, got []byte) {
+	tb.Helper()
+	got = Normalize(got)
+	if os.Getenv(UpdateEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("reading golden file: %v (run with %s=1 to create it)", err, UpdateEnv)
+	}
+	if !bytes.Equal(got, want) {
+		tb.Errorf("log output does not match %s:\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+// Quiet discards output held back from earlier in the test binary and
+// logs the output held back during the test with tb.Log if it fails.
+// Without -v, output while running under go test is held back rather
+// than written to stdout; see log.DumpTestOutput.
+func Quiet(tb testing.TB) {
+	tb.Helper()
+	log.DumpTestOutput(io.Discard)
+	tb.Cleanup(func() {
+		if !tb.Failed() {
+			log.DumpTestOutput(io.Discard)
+			return
+		}
+		var b bytes.Buffer
+		if n, _ := log.DumpTestOutput(&b); n > 0 {
+			tb.Logf("log output:\n%s", b.Bytes())
+		}
+	})
+}
+
+// RequireSchema checks every entry logged during the test against s,
+// failing the test for each one that breaks it, unless s has its own
+// OnViolation. The schema is removed when the test ends.
+func RequireSchema(tb testing.TB, s log.Schema) {
+	tb.Helper()
+	if s.OnViolation == nil {
+		s.OnViolation = func(err *log.SchemaError) {
+			if err.Caller != "" {
+				tb.Errorf("%v at %s", err, err.Caller)
+				return
+			}
+			tb.Error(err)
+		}
+	}
+	log.SetSchema(&s)
+	log.SetSchemaCheck(true)
+	tb.Cleanup(func() { log.SetSchema(nil) })
+}
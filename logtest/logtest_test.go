@@ -0,0 +1,102 @@
+package logtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	log "github.com/dangersalad/go-log"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, c := range []struct {
+		in, want string
+	}{
+		{"at 2024-05-01T12:30:45.123456Z done", "at <time> done"},
+		{"at 2024-05-01T12:30:45+02:00 done", "at <time> done"},
+		{"took 12ms", "took <duration>"},
+		{"took 1.5s", "took <duration>"},
+		{"took 563.16µs)", "took <duration>)"},
+		{"took 1m30s", "took <duration>"},
+		{"took 2h3m4.5s", "took <duration>"},
+		{"took=250ns next", "took=<duration> next"},
+		{"listening on 127.0.0.1:43817", "listening on 127.0.0.1:<port>"},
+		{"listening on [::1]:8080", "listening on [::1]:<port>"},
+		{"GET http://localhost:3000/x", "GET http://localhost:<port>/x"},
+		{"5 seconds, v1, 3 items", "5 seconds, v1, 3 items"},
+	} {
+		if got := string(Normalize([]byte(c.in))); got != c.want {
+			t.Errorf("Normalize(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+// recordingTB records failures instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Errorf(f string, a ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(f, a...))
+}
+
+func (r *recordingTB) Fatalf(f string, a ...interface{}) {
+	r.Errorf(f, a...)
+	runtime.Goexit()
+}
+
+// failures runs fn with a recordingTB on its own goroutine, so Fatalf
+// can stop it, and returns the failures it reported.
+func failures(t *testing.T, fn func(tb testing.TB)) []string {
+	r := &recordingTB{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r.failures
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "flow.golden")
+	out := []byte("main    |  request done (12ms)\n")
+
+	t.Setenv(UpdateEnv, "1")
+	AssertGolden(t, path, out)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "main    |  request done (<duration>)\n"; string(b) != want {
+		t.Errorf("golden file = %q, want %q", b, want)
+	}
+
+	t.Setenv(UpdateEnv, "")
+	AssertGolden(t, path, []byte("main    |  request done (3.2s)\n"))
+
+	if f := failures(t, func(tb testing.TB) {
+		AssertGolden(tb, path, []byte("main    |  request failed (12ms)\n"))
+	}); len(f) != 1 {
+		t.Errorf("mismatch reported %q, want one failure", f)
+	}
+	if f := failures(t, func(tb testing.TB) {
+		AssertGolden(tb, filepath.Join(t.TempDir(), "missing.golden"), out)
+	}); len(f) != 1 {
+		t.Errorf("missing golden file reported %q, want one failure", f)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	b := Capture(t)
+	prev := log.GetFormat()
+	log.SetFormat(log.FormatText)
+	defer log.SetFormat(prev)
+	log.NewLogger("cap", false).Info("captured")
+	if got, want := b.String(), "cap     |  captured\n"; got != want {
+		t.Errorf("captured %q, want %q", got, want)
+	}
+}
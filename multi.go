@@ -0,0 +1,80 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	outputsMu sync.Mutex
+	// outputs holds the []*output added with AddOutput, replaced on
+	// change.
+	outputs atomic.Value
+)
+
+// AddOutput writes all log output to w in format f as well as to the
+// main output, until remove is called. This lets a service keep its
+// text output on stdout while also writing JSON to a file or
+// collector, so consumers can move to JSON before the switch:
+//
+//	f, _ := os.OpenFile("app.json", os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+//	log.AddOutput(f, log.FormatJSON)
+//
+// Added outputs are reported by GetHealth. Settings such as SetColor
+// and SetFraming only apply to the main output.
+func AddOutput(w io.Writer, f Format) (remove func()) {
+	return AddOutputEncoder(w, FormatEncoder(f))
+}
+
+// AddOutputEncoder is AddOutput with an encoder instead of a format,
+// so each output can be rendered its own way, such as text on the
+// console and a custom format to a collector. See FormatEncoder for
+// the built in formats.
+func AddOutputEncoder(w io.Writer, enc Encoder) (remove func()) {
+	o := &output{w: w, custom: enc, facility: 1, set: true}
+	o.enc = o.newEncoder()
+	return addOutput(o)
+}
+
+func addOutput(o *output) (remove func()) {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+	old, _ := outputs.Load().([]*output)
+	outputs.Store(append(old[:len(old):len(old)], o))
+	var once sync.Once
+	return func() {
+		once.Do(func() { removeOutput(o) })
+	}
+}
+
+func removeOutput(o *output) {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+	old, _ := outputs.Load().([]*output)
+	keep := make([]*output, 0, len(old))
+	for _, x := range old {
+		if x != o {
+			keep = append(keep, x)
+		}
+	}
+	outputs.Store(keep)
+}
+
+// allOutputs returns the main output followed by the added ones.
+func allOutputs() []*output {
+	extra, _ := outputs.Load().([]*output)
+	return append([]*output{out}, extra...)
+}
+
+// writeAll writes e to the main output, or slog's handler, see
+// SetSlogDelegation, and the added ones.
+func writeAll(e *entry) {
+	if h := slogDelegate(); h == nil || !writeSlog(h, e) {
+		out.write(e)
+	}
+	extra, _ := outputs.Load().([]*output)
+	for _, o := range extra {
+		o.write(e)
+	}
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// OpKey is the field key for the operation name used by Begin.
+const OpKey = "op"
+
+// Op is an operation started with Begin, logged when it starts and
+// ends, like a lightweight trace span:
+//
+//	op := log.Begin(ctx, "sync-users")
+//	err := syncUsers(op.Context())
+//	op.End(err)
+type Op struct {
+	logger *Logger
+	ctx    context.Context
+	name   string
+	start  time.Time
+	ended  int32
+}
+
+// Begin starts an operation with the logger carried by ctx, see
+// FromContext, and logs that it started at the debug level. Entries
+// logged for the operation have an op field with its name.
+func Begin(ctx context.Context, name string) *Op {
+	l := FromContext(ctx).with(Str(OpKey, name))
+	op := &Op{
+		logger: l,
+		ctx:    NewContext(ctx, l),
+		name:   name,
+		start:  time.Now(),
+	}
+	l.debugf("%s started", name)
+	return op
+}
+
+// Context returns a context carrying the operation's logger, so
+// entries logged with FromContext during the operation have its op
+// field.
+func (op *Op) Context() context.Context {
+	return op.ctx
+}
+
+// Logger returns the operation's logger.
+func (op *Op) Logger() *Logger {
+	return op.logger
+}
+
+// End logs that the operation ended, with its duration, at the info
+// level if err is nil and at the error level with the error otherwise.
+// An error that IsCanceled reports is expected is logged at the info
+// level. Only the first call logs.
+func (op *Op) End(err error) {
+	if !atomic.CompareAndSwapInt32(&op.ended, 0, 1) {
+		return
+	}
+	d := Dur("duration", time.Since(op.start))
+	switch {
+	case err == nil:
+		op.logger.Log(InfoLevel, op.name+" done", d)
+	case IsCanceled(op.ctx, err):
+		op.logger.Log(InfoLevel, op.name+" canceled", d, Err(err))
+	default:
+		op.logger.Log(ErrorLevel, op.name+" failed", d, Err(err))
+	}
+}
@@ -0,0 +1,289 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format selects how entries are rendered.
+type Format string
+
+const (
+	// FormatText is the human readable column format. This is the
+	// default.
+	FormatText Format = "text"
+	// FormatJSON renders each entry as a JSON object on its own line.
+	// Fields that share a name with the entry's keys are renamed, see
+	// Logger.With.
+	FormatJSON Format = "json"
+)
+
+var out = &output{
+	w:        defaultWriter(),
+	tty:      defaultTerminal(),
+	enc:      newTextEncoder(false, nil),
+	format:   FormatText,
+	facility: 1,
+}
+
+// output serializes encoded entries to a writer.
+type output struct {
+	mu      sync.Mutex
+	w       io.Writer
+	enc     encoder
+	format  Format
+	color   bool
+	symbols map[Level]string
+	// custom is the encoder set with SetEncoder, used instead of the
+	// format.
+	custom Encoder
+	// facility and sdid configure FormatSyslog.
+	facility int
+	sdid     string
+	// file is the file opened by SetOutputFile, closed when the
+	// output changes.
+	file *os.File
+	// lock is set to flock file around writes.
+	lock bool
+	// tty is set if the output is a terminal.
+	tty *os.File
+	// set is true once SetOutput has been called, at which point Die
+	// writes to w instead of stderr.
+	set   bool
+	buf   bytes.Buffer
+	stats sinkStats
+	// framing delimits entries, using frameBuf as scratch space.
+	framing  Framing
+	frameBuf bytes.Buffer
+}
+
+// sinkStats counts the writes to a destination.
+type sinkStats struct {
+	written   uint64
+	dropped   uint64
+	lastWrite time.Time
+	lastErr   error
+	errTime   time.Time
+}
+
+func (s *sinkStats) record(err error) {
+	now := time.Now()
+	if err != nil {
+		s.dropped++
+		s.lastErr = err
+		s.errTime = now
+		return
+	}
+	s.written++
+	s.lastWrite = now
+}
+
+// SetOutput sets the destination for all log output. The default is
+// stdout.
+//
+// Die writes to stderr unless an output has been set.
+func SetOutput(w io.Writer) {
+	out.setWriter(w, nil)
+}
+
+// ResetOutput restores the default output, stdout, closing any file
+// set with SetOutputFile.
+func ResetOutput() {
+	out.setWriter(defaultWriter(), nil)
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.set = false
+}
+
+// SetOutputFile sets the output to the file at path, opened for
+// appending and created if needed.
+//
+// Each entry is written with a single write to a file opened with
+// O_APPEND, so on local file systems entries from several processes
+// appending to the same file do not interleave. Use SetFileLocking
+// where that is not enough, such as for very large entries or network
+// file systems.
+func SetOutputFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	out.setWriter(f, f)
+	return nil
+}
+
+// SetFileLocking enables or disables taking an exclusive advisory
+// lock (flock) on the file set with SetOutputFile around every write,
+// for processes that share a log file. Every process writing to the
+// file must enable it. Locking is not supported on all platforms, where
+// this does nothing.
+//
+// It does not apply to RotatingFile and SplitFiles, which are meant
+// for a single process: each process would rotate the file on its own.
+func SetFileLocking(enabled bool) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.lock = enabled
+}
+
+// SetFormat sets the format of all log output, including Die. The
+// default is FormatText.
+func SetFormat(f Format) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.format = f
+	out.custom = nil
+	out.enc = out.newEncoder()
+}
+
+// SetColor enables or disables coloring the level column of the text
+// format with ANSI escapes.
+func SetColor(enabled bool) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.color = enabled
+	out.enc = out.newEncoder()
+}
+
+// SetSymbols renders the level column of the text format with the
+// given symbols, such as DefaultSymbols, instead of the three letter
+// names. The level column is then shown for all loggers, not only
+// debug ones. Levels without a symbol keep their names. A nil map turns
+// symbols off, which is the default.
+func SetSymbols(symbols map[Level]string) {
+	var cp map[Level]string
+	if symbols != nil {
+		cp = make(map[Level]string, len(symbols))
+		for k, v := range symbols {
+			cp[k] = v
+		}
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.symbols = cp
+	out.enc = out.newEncoder()
+}
+
+// GetFormat returns the current output format.
+func GetFormat() Format {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	return out.format
+}
+
+// ParseFormat parses a format name.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatText, FormatJSON, FormatSyslog, FormatLine:
+		return f, nil
+	}
+	return FormatText, fmt.Errorf("unknown log format %q", s)
+}
+
+func (o *output) newEncoder() encoder {
+	if o.custom != nil {
+		return internalEncoder(o.custom)
+	}
+	switch o.format {
+	case FormatJSON:
+		return jsonEncoder{}
+	case FormatSyslog:
+		return newSyslogEncoder(o.facility, o.sdid)
+	case FormatLine:
+		return newLineEncoder(o.color)
+	}
+	return newTextEncoder(o.color, o.symbols)
+}
+
+func (o *output) setWriter(w io.Writer, f *os.File) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file != nil {
+		o.file.Close()
+	}
+	o.w = w
+	o.file = f
+	o.tty = terminalFile(w)
+	o.set = true
+	o.stats = sinkStats{}
+}
+
+// name describes the destination for health reports.
+func (o *output) name() string {
+	switch {
+	case o.file != nil:
+		return o.file.Name()
+	case o.w == os.Stdout:
+		return "stdout"
+	case o.w == os.Stderr:
+		return "stderr"
+	case o.w == io.Writer(testOutput):
+		return "test buffer"
+	}
+	if s, ok := o.w.(sinkWriter); ok {
+		return fmt.Sprintf("%T", s.s)
+	}
+	return fmt.Sprintf("%T", o.w)
+}
+
+func (o *output) write(e *entry) {
+	if err := o.encodeWrite(e); err != nil {
+		reportError(err)
+	}
+}
+
+// encodeWrite encodes e and writes it with the output locked. The lock
+// is released by a defer, so a panicking Encoder or Sink does not
+// leave it held.
+func (o *output) encodeWrite(e *entry) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf.Reset()
+	o.enc.encode(&o.buf, e)
+	o.fitTerminal(e)
+	o.frame()
+	w := o.w
+	if e.level == FatalLevel && !o.set {
+		w = os.Stderr
+	}
+	if ew, ok := w.(entryWriter); ok {
+		err = ew.writeEntry(e, o.buf.Bytes())
+	} else if o.lock && o.file != nil && w == io.Writer(o.file) {
+		err = o.writeLocked()
+	} else {
+		_, err = w.Write(o.buf.Bytes())
+	}
+	o.stats.record(err)
+	if err != nil {
+		err = &SinkError{Sink: o.name(), Err: err}
+		if w != os.Stderr && stderrFallback() {
+			os.Stderr.Write(o.buf.Bytes())
+		}
+	}
+	if e.level == FatalLevel {
+		// The process exits next, so write out anything the writer
+		// buffers, such as a CompressWriter's stream, and sync files.
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+		if s, ok := w.(interface{ Sync() error }); ok {
+			s.Sync()
+		}
+	}
+	return err
+}
+
+// writeLocked writes the buffer to the file while holding its lock.
+func (o *output) writeLocked() error {
+	if err := lockFile(o.file); err != nil {
+		return err
+	}
+	defer unlockFile(o.file)
+	_, err := o.file.Write(o.buf.Bytes())
+	return err
+}
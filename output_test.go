@@ -0,0 +1,165 @@
+package log
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	sharedFileEnv   = "LOG_TEST_SHARED_FILE"
+	sharedWriters   = 4
+	sharedEntries   = 200
+	sharedEntrySize = 64 << 10
+)
+
+// TestSharedFileWriter is the child process of the shared file tests.
+func TestSharedFileWriter(t *testing.T) {
+	path := os.Getenv(sharedFileEnv)
+	if path == "" {
+		t.Skip("only run as a child process of the shared file tests")
+	}
+	if err := SetOutputFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetOutput()
+	SetFormat(FormatText)
+	SetFileLocking(true)
+	defer SetFileLocking(false)
+	l := NewLogger(fmt.Sprintf("w%d", os.Getpid()%100000), false)
+	payload := strings.Repeat("x", sharedEntrySize)
+	for i := 0; i < sharedEntries; i++ {
+		l.Log(InfoLevel, "entry", Int("n", i), Str("payload", payload))
+	}
+}
+
+func TestSharedFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts several processes")
+	}
+	path := filepath.Join(t.TempDir(), "shared.log")
+	cmds := make([]*exec.Cmd, sharedWriters)
+	for i := range cmds {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestSharedFileWriter$")
+		cmd.Env = append(os.Environ(), sharedFileEnv+"="+path)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds[i] = cmd
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 2*sharedEntrySize)
+	lines := 0
+	for s.Scan() {
+		lines++
+		line := s.Text()
+		if !strings.Contains(line, "|  entry n=") || !strings.HasSuffix(line, "payload="+strings.Repeat("x", sharedEntrySize)) {
+			t.Fatalf("line %d is interleaved: %.80q...", lines, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := sharedWriters * sharedEntries; lines != want {
+		t.Errorf("file has %d entries, want %d", lines, want)
+	}
+}
+
+func TestFileLockingWaitsForLock(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd":
+	default:
+		t.Skip("flock is not supported on " + runtime.GOOS)
+	}
+	path := filepath.Join(t.TempDir(), "locked.log")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSharedFileWriter$")
+	cmd.Env = append(os.Environ(), sharedFileEnv+"="+path)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockFile(f)
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 0 {
+		t.Errorf("child wrote %d bytes while the file was locked", fi.Size())
+	}
+	if fi, _ = os.Stat(path); fi.Size() == 0 {
+		t.Error("child wrote nothing once the file was unlocked")
+	}
+}
+
+const dieCompressEnv = "LOG_TEST_DIE_COMPRESS"
+
+func TestDieFlushesCompressWriter(t *testing.T) {
+	if path := os.Getenv(dieCompressEnv); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		SetOutput(NewCompressWriter(f, Gzip(gzip.BestSpeed), time.Hour))
+		Info("before")
+		Die(errors.New("boom"), 3)
+	}
+	path := filepath.Join(t.TempDir(), "die.log.gz")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDieFlushesCompressWriter$")
+	cmd.Env = append(os.Environ(), dieCompressEnv+"="+path)
+	var ee *exec.ExitError
+	if err := cmd.Run(); !errors.As(err, &ee) || ee.ExitCode() != 3 {
+		t.Fatalf("Die exited with %v, want exit status 3", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The stream was never closed, so it ends in an unexpected EOF
+	// after the flushed entries.
+	b, err := io.ReadAll(zr)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "before") || !strings.Contains(string(b), "boom") {
+		t.Errorf("output = %q, want the info entry and the Die entry", b)
+	}
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Profile is a preset of output settings.
+type Profile struct {
+	Format Format
+	// Color colors the level column of the text format.
+	Color bool
+	// Debug enables debug output, as SetDebug.
+	Debug bool
+	// Caller adds the calling file and line to every entry. Without a
+	// profile the caller is only added for debug loggers.
+	Caller bool
+	Level  Level
+	// Symbols renders levels in the text format with DefaultSymbols.
+	Symbols bool
+	// FormatCheck warns about format verb mistakes, as SetFormatCheck.
+	FormatCheck bool
+	// SchemaCheck checks entries against the schema, as
+	// SetSchemaCheck.
+	SchemaCheck bool
+}
+
+var (
+	// Development is for working locally: colored text with debug
+	// output, callers, and format and schema checks.
+	Development = Profile{
+		Format:      FormatText,
+		Color:       true,
+		Debug:       true,
+		Caller:      true,
+		Level:       DebugLevel,
+		FormatCheck: true,
+		SchemaCheck: true,
+	}
+	// Kube is for development clusters read with kubectl logs or k9s:
+	// Development, but in the single line FormatLine.
+	Kube = Profile{
+		Format:      FormatLine,
+		Color:       true,
+		Debug:       true,
+		Caller:      true,
+		Level:       DebugLevel,
+		FormatCheck: true,
+		SchemaCheck: true,
+	}
+	// Production is for shipped logs: JSON at the info level without
+	// callers.
+	Production = Profile{
+		Format: FormatJSON,
+		Level:  InfoLevel,
+	}
+)
+
+const (
+	callerAuto int32 = iota
+	callerOn
+	callerOff
+)
+
+var callerMode = callerAuto
+
+// SetProfile applies all the settings of p.
+//
+// At startup the profile is picked from the Environment: Development
+// for "dev", "development", "test" or "testing", Kube for "kube" or
+// "k8s" and Production for "prod" or "production". Without one, it
+// depends on LOG_PROFILE and whether stdout is a terminal; see the
+// package documentation. The other LOG_* variables override the
+// profile.
+func SetProfile(p Profile) {
+	SetFormat(p.Format)
+	SetColor(p.Color)
+	SetDebug(p.Debug)
+	SetCaller(p.Caller)
+	SetLevel(p.Level)
+	SetFormatCheck(p.FormatCheck)
+	SetSchemaCheck(p.SchemaCheck)
+	if p.Symbols {
+		SetSymbols(DefaultSymbols)
+	} else {
+		SetSymbols(nil)
+	}
+}
+
+// ProfileFor returns the profile for an environment name, if there
+// is one.
+func ProfileFor(env string) (Profile, bool) {
+	switch strings.ToLower(env) {
+	case "dev", "development", "test", "testing":
+		return Development, true
+	case "kube", "k8s":
+		return Kube, true
+	case "prod", "production":
+		return Production, true
+	}
+	return Profile{}, false
+}
+
+// SetCaller enables or disables adding the caller to every entry,
+// regardless of debug.
+func SetCaller(enabled bool) {
+	v := callerOff
+	if enabled {
+		v = callerOn
+	}
+	atomic.StoreInt32(&callerMode, v)
+}
+
+func callerEnabled(debug bool) bool {
+	switch atomic.LoadInt32(&callerMode) {
+	case callerOn:
+		return true
+	case callerOff:
+		return false
+	}
+	return debug
+}
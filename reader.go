@@ -0,0 +1,105 @@
+package log
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// DebugReader logs up to limit bytes from r with the default logger.
+// See Logger.DebugReader.
+func DebugReader(label string, r io.Reader, limit int64) io.Reader {
+	return defaultLogger.debugReader(label, r, limit, getCaller())
+}
+
+// DebugReader returns a reader that yields everything r does and logs
+// the first limit bytes read through it as a debug entry. Bytes are
+// captured as the caller reads them, so streaming and long-poll bodies
+// are passed on as they arrive. Only limit bytes are held in memory,
+// so it is safe for request bodies and large files:
+//
+//	r.Body = io.NopCloser(logger.DebugReader("request body", r.Body, 4096))
+//
+// The entry is logged once limit bytes have been read and there is
+// more, when r returns an error, such as io.EOF, or when the reader is
+// closed. The returned reader has a Close method that logs what was
+// read so far, if it wasn't logged yet, and closes r if it is an
+// io.Closer.
+//
+// If debug is disabled, r is returned as is. The data is logged in a
+// data field if it is valid UTF-8, otherwise hex encoded in a hex
+// field, with the number of bytes logged and whether there was more.
+func (l *Logger) DebugReader(label string, r io.Reader, limit int64) io.Reader {
+	return l.debugReader(label, r, limit, getCaller())
+}
+
+func (l *Logger) debugReader(label string, r io.Reader, limit int64, site string) io.Reader {
+	if !l.enabled(DebugLevel) || limit <= 0 {
+		return r
+	}
+	return &debugReader{l: l, label: label, site: site, r: r, limit: limit}
+}
+
+// debugReader captures the head of r as it is read.
+type debugReader struct {
+	l           *Logger
+	label, site string
+	r           io.Reader
+	limit       int64
+
+	head      bytes.Buffer
+	truncated bool
+	logged    bool
+}
+
+func (d *debugReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if d.logged {
+		return n, err
+	}
+	if room := d.limit - int64(d.head.Len()); room > 0 {
+		if int64(n) > room {
+			d.head.Write(p[:room])
+			d.truncated = true
+		} else {
+			d.head.Write(p[:n])
+		}
+	} else if n > 0 {
+		d.truncated = true
+	}
+	if d.truncated || err != nil {
+		d.log(err)
+	}
+	return n, err
+}
+
+// Close logs the head if it wasn't logged yet and closes r.
+func (d *debugReader) Close() error {
+	d.log(nil)
+	if c, ok := d.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (d *debugReader) log(err error) {
+	if d.logged {
+		return
+	}
+	d.logged = true
+	b := d.head.Bytes()
+	fs := []Field{Any("bytes", len(b)), Any("truncated", d.truncated)}
+	if utf8.Valid(b) {
+		fs = append(fs, Any("data", string(b)))
+	} else {
+		fs = append(fs, Any("hex", hex.EncodeToString(b)))
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		fs = append(fs, Any("read_error", err.Error()))
+	}
+	d.l.logFrom(d.site, DebugLevel, d.label, fs...)
+	// The head is not needed once it is logged.
+	d.head = bytes.Buffer{}
+}
@@ -0,0 +1,131 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func debugReaderLogger(t *testing.T) (*Logger, *syncBuffer) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	l := NewLogger("io", false)
+	l.SetDebug(true)
+	return l, &buf
+}
+
+func TestDebugReaderStreams(t *testing.T) {
+	l, buf := debugReaderLogger(t)
+	pr, pw := io.Pipe()
+	r := l.DebugReader("body", pr, 64)
+
+	go pw.Write([]byte("hello"))
+	got := make(chan string)
+	go func() {
+		p := make([]byte, 64)
+		n, _ := r.Read(p)
+		got <- string(p[:n])
+	}()
+	select {
+	case s := <-got:
+		if s != "hello" {
+			t.Fatalf("first read = %q, want hello", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read waited for more than the source had written")
+	}
+	if out := buf.String(); out != "" {
+		t.Fatalf("logged %q before the body was read", out)
+	}
+
+	go func() {
+		pw.Write([]byte(" world"))
+		pw.Close()
+	}()
+	rest, err := io.ReadAll(r)
+	if err != nil || string(rest) != " world" {
+		t.Fatalf("rest = %q, %v", rest, err)
+	}
+	if out := buf.String(); !strings.Contains(out, "body bytes=11 truncated=false data=hello world\n") {
+		t.Errorf("output = %q, want the whole body", out)
+	}
+}
+
+func TestDebugReader(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		src    io.Reader
+		limit  int64
+		close  bool
+		want   string
+		output string
+	}{
+		{
+			name:   "truncated",
+			src:    iotest.OneByteReader(strings.NewReader("abcdefgh")),
+			limit:  4,
+			want:   "abcdefgh",
+			output: "bytes=4 truncated=true data=abcd",
+		},
+		{
+			name:   "exact",
+			src:    strings.NewReader("abcd"),
+			limit:  4,
+			want:   "abcd",
+			output: "bytes=4 truncated=false data=abcd",
+		},
+		{
+			name:   "binary",
+			src:    strings.NewReader("\xff\x00"),
+			limit:  4,
+			want:   "\xff\x00",
+			output: "bytes=2 truncated=false hex=ff00",
+		},
+		{
+			name:   "read error",
+			src:    io.MultiReader(strings.NewReader("ab"), iotest.ErrReader(errors.New("reset"))),
+			limit:  4,
+			want:   "ab",
+			output: "bytes=2 truncated=false data=ab read_error=reset",
+		},
+		{
+			name:   "closed early",
+			src:    io.LimitReader(strings.NewReader("abcdefgh"), 3),
+			limit:  8,
+			close:  true,
+			want:   "abc",
+			output: "bytes=3 truncated=false data=abc",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			l, buf := debugReaderLogger(t)
+			r := l.DebugReader("body", c.src, c.limit)
+			var got []byte
+			if c.close {
+				got = make([]byte, 3)
+				io.ReadFull(r, got)
+				r.(io.Closer).Close()
+			} else {
+				got, _ = io.ReadAll(r)
+			}
+			if string(got) != c.want {
+				t.Errorf("read %q, want %q", got, c.want)
+			}
+			out := buf.String()
+			if strings.Count(out, "\n") != 1 || !strings.Contains(out, "body "+c.output) {
+				t.Errorf("output = %q, want one entry with %q", out, c.output)
+			}
+		})
+	}
+}
+
+func TestDebugReaderDisabled(t *testing.T) {
+	src := strings.NewReader("abc")
+	if r := NewLogger("io", false).DebugReader("body", src, 8); r != io.Reader(src) {
+		t.Errorf("DebugReader wrapped r with debug off")
+	}
+}
@@ -0,0 +1,205 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// recentLevel is the minimum level kept plus one, or 0 if recent
+// entries are not kept.
+var recentLevel int32
+
+var recent = &ringBuffer{}
+
+// KeepRecent keeps the last n entries at lvl or above in memory,
+// including entries below the level that is logged, so they can be
+// looked at after the fact, such as in a crash report; see
+// SetCrashDir. Lazy fields are evaluated for every entry kept. An n of
+// 0 or less stops keeping entries, which is the default.
+func KeepRecent(n int, lvl Level) {
+	recent.resize(n)
+	if n <= 0 {
+		atomic.StoreInt32(&recentLevel, 0)
+		return
+	}
+	atomic.StoreInt32(&recentLevel, int32(lvl)+1)
+}
+
+// RecentEntries returns the entries kept by KeepRecent, oldest first.
+func RecentEntries() []Entry {
+	es := recent.entries()
+	r := make([]Entry, len(es))
+	for i := range es {
+		r[i] = *es[i].export()
+	}
+	return r
+}
+
+// RecentHandler returns a handler that serves the entries kept by
+// KeepRecent as a JSON array, oldest first, in the JSON format's
+// layout, so recent debug entries of a live process can be looked at
+// even when only higher levels are shipped. They are filtered by the
+// query parameters:
+//
+//	level   the minimum level, such as "debug" or "warn"
+//	prefix  the logger prefix
+//	since   an RFC 3339 time, or a duration back from now such as "5m"
+//	until   an RFC 3339 time, or a duration back from now
+//	limit   the number of entries, the newest ones matching
+//
+// Invalid parameters get a 400 response. Entries can hold anything that
+// was logged, so serve this on an admin port only.
+func RecentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := parseRecentFilter(r.URL.Query(), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var match []entry
+		for _, e := range recent.entries() {
+			if f.match(&e) {
+				match = append(match, e)
+			}
+		}
+		if f.limit > 0 && len(match) > f.limit {
+			match = match[len(match)-f.limit:]
+		}
+		var b bytes.Buffer
+		b.WriteByte('[')
+		for i := range match {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+			jsonEncoder{}.encode(&b, &match[i])
+			b.Truncate(b.Len() - 1)
+		}
+		b.WriteString("\n]\n")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b.Bytes())
+	})
+}
+
+type recentFilter struct {
+	level        Level
+	prefix       string
+	since, until time.Time
+	limit        int
+}
+
+func parseRecentFilter(q url.Values, now time.Time) (recentFilter, error) {
+	f := recentFilter{level: DebugLevel}
+	var err error
+	if v := q.Get("level"); v != "" {
+		if f.level, err = ParseLevel(v); err != nil {
+			return f, err
+		}
+	}
+	f.prefix = q.Get("prefix")
+	if f.since, err = parseRecentTime(q.Get("since"), now); err != nil {
+		return f, fmt.Errorf("since: %w", err)
+	}
+	if f.until, err = parseRecentTime(q.Get("until"), now); err != nil {
+		return f, fmt.Errorf("until: %w", err)
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.limit, err = strconv.Atoi(v); err != nil || f.limit < 0 {
+			return f, fmt.Errorf("limit: invalid count %q", v)
+		}
+	}
+	return f, nil
+}
+
+// parseRecentTime parses an RFC 3339 time or a duration before now.
+func parseRecentTime(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, want RFC 3339 or a duration", v)
+	}
+	return t, nil
+}
+
+func (f *recentFilter) match(e *entry) bool {
+	if e.level < f.level {
+		return false
+	}
+	if f.prefix != "" && e.prefix != f.prefix {
+		return false
+	}
+	if !f.since.IsZero() && e.time.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && e.time.After(f.until) {
+		return false
+	}
+	return true
+}
+
+// recentWanted reports whether an entry at lvl is kept.
+func recentWanted(lvl Level) bool {
+	v := atomic.LoadInt32(&recentLevel)
+	return v != 0 && int32(lvl)+1 >= v
+}
+
+// ringBuffer holds copies of the last entries.
+type ringBuffer struct {
+	mu   sync.Mutex
+	buf  []entry
+	next int
+	full bool
+}
+
+func (r *ringBuffer) resize(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	r.buf = make([]entry, n)
+	r.next = 0
+	r.full = false
+}
+
+// add keeps a copy of e, if recent entries are wanted at its level.
+func (r *ringBuffer) add(e *entry) {
+	if !recentWanted(e.level) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.buf) == 0 {
+		return
+	}
+	c := *e
+	c.prefixCol = ""
+	c.fields = append([]Field(nil), e.fields...)
+	c.groups = append([]string(nil), e.groups...)
+	r.buf[r.next] = c
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// entries returns the kept entries, oldest first.
+func (r *ringBuffer) entries() []entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]entry(nil), r.buf[:r.next]...)
+	}
+	return append(append([]entry(nil), r.buf[r.next:]...), r.buf[:r.next]...)
+}
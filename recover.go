@@ -0,0 +1,139 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// PanicKey is the field key for the recovered value in entries logged
+// by Recover.
+const PanicKey = "panic"
+
+// PanicTypeKey is the field key for the Go type of the recovered value.
+const PanicTypeKey = "panic_type"
+
+// Recover recovers a panic and logs it at the error level with the
+// default logger. See Logger.Recover.
+func Recover() {
+	if r := recover(); r != nil {
+		defaultLogger.logPanic(r, nil)
+	}
+}
+
+// RecoverWith is Recover with fields added to the entry.
+func RecoverWith(fs Fields) {
+	if r := recover(); r != nil {
+		defaultLogger.logPanic(r, fs)
+	}
+}
+
+// RecoverRepanic logs a panic like Recover and then panics again with
+// the same value, for when the panic should still crash the process or
+// be handled further up.
+func RecoverRepanic() {
+	if r := recover(); r != nil {
+		defaultLogger.logPanic(r, nil)
+		panic(r)
+	}
+}
+
+// Recover recovers a panic and logs it at the error level with the
+// panic value, its type and the stack of the panicking goroutine.
+// Errors are logged by their message and structs with their field
+// names. It has to be
+// deferred directly:
+//
+//	defer logger.Recover()
+func (l *Logger) Recover() {
+	if r := recover(); r != nil {
+		l.logPanic(r, nil)
+	}
+}
+
+// RecoverWith is Recover with fields added to the entry.
+func (l *Logger) RecoverWith(fs Fields) {
+	if r := recover(); r != nil {
+		l.logPanic(r, fs)
+	}
+}
+
+// RecoverRepanic logs a panic like Recover and then panics again with
+// the same value.
+func (l *Logger) RecoverRepanic() {
+	if r := recover(); r != nil {
+		l.logPanic(r, nil)
+		panic(r)
+	}
+}
+
+func (l *Logger) logPanic(r interface{}, extra Fields) {
+	if !l.enabled(ErrorLevel) {
+		return
+	}
+	v := panicValue(r)
+	fs := append([]Field{Str(PanicKey, v), Str(PanicTypeKey, fmt.Sprintf("%T", r))}, sortedFields(extra)...)
+	fs = append(fs, Str(StackKey, panicStack()))
+	l.writeFields(ErrorLevel, "panic: "+v, fs)
+}
+
+// panicValue renders a recovered value: the message of errors,
+// including runtime errors, and structs with their field names.
+func panicValue(r interface{}) (s string) {
+	defer func() {
+		if p := recover(); p != nil {
+			s = fmt.Sprintf("%T (panicked while formatting: %v)", r, p)
+		}
+	}()
+	switch v := r.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case string:
+		return v
+	}
+	return fmt.Sprintf("%+v", r)
+}
+
+// panicStack returns the stack of the panicking goroutine from the
+// frame that panicked, formatted like Stack, without the runtime's own
+// frames, such as the ones raising a runtime error.
+func panicStack() string {
+	pcs := make([]uintptr, DefaultStackDepth+16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	seen, written := false, 0
+	for written < DefaultStackDepth {
+		f, more := frames.Next()
+		if !seen {
+			// Skip the deferred call and the runtime's panic frames.
+			seen = f.Function == "runtime.gopanic"
+			if !more {
+				break
+			}
+			continue
+		}
+		if strings.HasPrefix(f.Function, "runtime.") {
+			if !more {
+				break
+			}
+			continue
+		}
+		if written > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(f.Function)
+		b.WriteByte(' ')
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		written++
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type panicPoint struct{ X, Y int }
+
+type panicStringer struct{}
+
+func (panicStringer) String() string { return "stringer" }
+
+// panicWith panics with v from a frame the stack should start at, or
+// with a runtime error if v is nil.
+func panicWith(v interface{}) {
+	if v == nil {
+		var m map[string]int
+		m["x"] = 1
+	}
+	panic(v)
+}
+
+// recoverEntry runs panics, which recovers its own panic with l, and
+// returns the logged entry.
+func recoverEntry(t *testing.T, panics func(l *Logger)) map[string]interface{} {
+	t.Helper()
+	keepSettings(t)
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	SetFormat(FormatJSON)
+
+	panics(NewLogger("rec", false))
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &m); err != nil {
+		t.Fatalf("entry %q: %v", buf.String(), err)
+	}
+	return m
+}
+
+func TestRecover(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		v        interface{}
+		msg, typ string
+	}{
+		{"string", "boom", "boom", "string"},
+		{"error", errors.New("failed"), "failed", "*errors.errorString"},
+		{"stringer", panicStringer{}, "stringer", "log.panicStringer"},
+		{"struct", panicPoint{1, 2}, "{X:1 Y:2}", "log.panicPoint"},
+		{"int", 42, "42", "int"},
+		{"runtime error", nil, "assignment to entry in nil map", "runtime."},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			m := recoverEntry(t, func(l *Logger) {
+				defer l.Recover()
+				panicWith(c.v)
+			})
+			if m["level"] != "error" || m["msg"] != "panic: "+c.msg || m[PanicKey] != c.msg {
+				t.Errorf("entry = %v, want an error entry for %q", m, c.msg)
+			}
+			if typ, _ := m[PanicTypeKey].(string); !strings.HasPrefix(typ, c.typ) {
+				t.Errorf("%s = %q, want %q", PanicTypeKey, typ, c.typ)
+			}
+			stack, _ := m[StackKey].(string)
+			first, _, _ := strings.Cut(stack, "\n")
+			if !strings.HasPrefix(first, "github.com/dangersalad/go-log.panicWith ") || !strings.Contains(first, "recover_test.go:") {
+				t.Errorf("stack starts at %q, want panicWith\n%s", first, stack)
+			}
+		})
+	}
+}
+
+func TestRecoverWith(t *testing.T) {
+	m := recoverEntry(t, func(l *Logger) {
+		defer l.RecoverWith(Fields{"job": "sync"})
+		panicWith("boom")
+	})
+	if m["job"] != "sync" || m[PanicKey] != "boom" {
+		t.Errorf("entry = %v, want the job field", m)
+	}
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		recover func()
+		l       func() *Logger
+	}{
+		{"logger", nil, func() *Logger { return NewLogger("rec", false) }},
+		{"default logger", RecoverRepanic, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			keepSettings(t)
+			var buf syncBuffer
+			SetOutput(&buf)
+			t.Cleanup(ResetOutput)
+
+			v := errors.New("failed")
+			var got interface{}
+			func() {
+				defer func() { got = recover() }()
+				if c.l != nil {
+					defer c.l().RecoverRepanic()
+				} else {
+					defer c.recover()
+				}
+				panicWith(v)
+			}()
+			if got != v {
+				t.Errorf("recovered %v after RecoverRepanic, want the original value", got)
+			}
+			if out := buf.String(); strings.Count(out, "panic: failed") != 1 || !strings.Contains(out, "panicWith") {
+				t.Errorf("output = %q, want the panic logged once with its stack", out)
+			}
+		})
+	}
+}
+
+func TestRecoverLevel(t *testing.T) {
+	keepSettings(t)
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	SetLevel(FatalLevel)
+
+	func() {
+		defer NewLogger("rec", false).Recover()
+		panicWith("boom")
+	}()
+	if out := buf.String(); out != "" {
+		t.Errorf("output = %q with the level above error", out)
+	}
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces redacted query values and masked path segments in
+// logged URLs.
+const Redacted = "REDACTED"
+
+// DefaultQueryDenylist is a basic set of query parameters that
+// commonly carry credentials, for use with WithQueryDenylist.
+var DefaultQueryDenylist = []string{
+	"access_token",
+	"api_key",
+	"apikey",
+	"auth",
+	"code",
+	"key",
+	"password",
+	"secret",
+	"signature",
+	"sig",
+	"token",
+}
+
+// WithQueryDenylist redacts the values of the named query parameters
+// in logged URLs. Names are matched case insensitively.
+func WithQueryDenylist(names ...string) HTTPOption {
+	return func(o *httpOptions) {
+		o.queryDeny = nameSet(names)
+	}
+}
+
+// WithQueryAllowlist redacts the values of all query parameters in
+// logged URLs except the named ones. Names are matched case
+// insensitively.
+func WithQueryAllowlist(names ...string) HTTPOption {
+	return func(o *httpOptions) {
+		o.queryAllow = nameSet(names)
+	}
+}
+
+// WithPathMask replaces path segments of logged URLs that fully match
+// re with Redacted, so IDs and tokens embedded in paths are not
+// logged. re does not need to be anchored: [0-9]+ masks "42" but not
+// "v1".
+//
+//	log.WithPathMask(regexp.MustCompile(`[0-9]+|[0-9a-f-]{36}`))
+func WithPathMask(re *regexp.Regexp) HTTPOption {
+	full := regexp.MustCompile(`^(?:` + re.String() + `)$`)
+	return func(o *httpOptions) {
+		o.pathMask = full
+	}
+}
+
+func nameSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[strings.ToLower(n)] = true
+	}
+	return set
+}
+
+// logURL returns the URL as it should be logged, without any user
+// name and password. The URL is only copied if something needs to be
+// removed or redacted.
+func (o *httpOptions) logURL(u *url.URL) *url.URL {
+	if u.User == nil && o.queryDeny == nil && o.queryAllow == nil && o.pathMask == nil {
+		return u
+	}
+	c := *u
+	c.User = nil
+	if o.pathMask != nil {
+		c.Path = o.maskPath(c.Path)
+		c.RawPath = ""
+	}
+	if c.RawQuery != "" && (o.queryDeny != nil || o.queryAllow != nil) {
+		c.RawQuery = o.redactQuery(c.RawQuery)
+	}
+	return &c
+}
+
+func (o *httpOptions) maskPath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		if s != "" && o.pathMask.MatchString(s) {
+			segs[i] = Redacted
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// redactQuery redacts values in the raw query, keeping the order and
+// encoding of everything else.
+func (o *httpOptions) redactQuery(q string) string {
+	parts := strings.Split(q, "&")
+	for i, p := range parts {
+		k, _, hasValue := strings.Cut(p, "=")
+		name, err := url.QueryUnescape(k)
+		if err != nil {
+			name = k
+		}
+		if o.redactParam(strings.ToLower(name)) && hasValue {
+			parts[i] = k + "=" + Redacted
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func (o *httpOptions) redactParam(name string) bool {
+	if o.queryAllow != nil && !o.queryAllow[name] {
+		return true
+	}
+	return o.queryDeny[name]
+}
@@ -0,0 +1,24 @@
+package log
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+func TestPathMask(t *testing.T) {
+	for _, c := range []struct {
+		re, path, want string
+	}{
+		{`[0-9]+`, "/api/v1/users/42", "/api/v1/users/REDACTED"},
+		{`^[0-9]+$`, "/api/v1/users/42", "/api/v1/users/REDACTED"},
+		{`a|ab`, "/ab/abc", "/REDACTED/abc"},
+		{`[0-9a-f-]{36}`, "/orders/0b5c9a8e-2a4b-4c1b-9d7e-3f6a1e2b4c5d/items", "/orders/REDACTED/items"},
+	} {
+		o := newHTTPOptions([]HTTPOption{WithPathMask(regexp.MustCompile(c.re))})
+		u, _ := url.Parse(c.path)
+		if got := o.logURL(u).Path; got != c.want {
+			t.Errorf("WithPathMask(%q) on %s = %s, want %s", c.re, c.path, got, c.want)
+		}
+	}
+}
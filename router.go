@@ -0,0 +1,98 @@
+package log
+
+import (
+	"io"
+	"sync"
+)
+
+// entryWriter is implemented by writers that need the entry being
+// written, not only its encoding. output calls writeEntry instead of
+// Write for them.
+type entryWriter interface {
+	writeEntry(e *entry, p []byte) error
+}
+
+// Router is a writer that picks the destination of each entry by the
+// value of one of its fields, such as writing each tenant's entries to
+// its own file:
+//
+//	r := log.NewRouter("tenant_id", os.Stdout)
+//	r.Route("acme", acmeFile)
+//	log.SetOutput(r)
+//	log.WithField("tenant_id", "acme").Info("billing run")
+//
+// Entries without the field, or with a value that has no route, go to
+// the fallback writer, as does anything written to the Router directly.
+type Router struct {
+	key      string
+	fallback io.Writer
+	mu       sync.RWMutex
+	routes   map[string]io.Writer
+	rules    []func(value string) io.Writer
+}
+
+// NewRouter returns a Router keyed on the field key.
+func NewRouter(key string, fallback io.Writer) *Router {
+	return &Router{
+		key:      key,
+		fallback: fallback,
+		routes:   map[string]io.Writer{},
+	}
+}
+
+// Route sends entries with the field set to value to w. A nil w
+// removes the route.
+func (r *Router) Route(value string, w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w == nil {
+		delete(r.routes, value)
+		return
+	}
+	r.routes[value] = w
+}
+
+// RouteFunc adds a rule for values without a route. Rules are tried in
+// the order they were added and the first to return a writer is used.
+// Rules are called for every entry they match, so one that opens a
+// file should keep it, or add it with Route.
+func (r *Router) RouteFunc(rule func(value string) io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, rule)
+}
+
+// Write writes p to the fallback writer.
+func (r *Router) Write(p []byte) (int, error) {
+	return r.fallback.Write(p)
+}
+
+func (r *Router) writeEntry(e *entry, p []byte) error {
+	_, err := r.dest(e).Write(p)
+	return err
+}
+
+func (r *Router) dest(e *entry) io.Writer {
+	var value string
+	found := false
+	for _, f := range e.fields {
+		if f.key == r.key {
+			value = f.stringValue()
+			found = true
+		}
+	}
+	if !found {
+		return r.fallback
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if w, ok := r.routes[value]; ok {
+		return w
+	}
+	for _, rule := range r.rules {
+		if w := rule(value); w != nil {
+			return w
+		}
+	}
+	return r.fallback
+}
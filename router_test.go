@@ -0,0 +1,75 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	var fallback, acme, globex, rule bytes.Buffer
+	r := NewRouter("tenant", &fallback)
+	r.Route("acme", &acme)
+	r.Route("globex", &globex)
+	r.Route("globex", nil)
+	r.RouteFunc(func(v string) io.Writer { return nil })
+	r.RouteFunc(func(v string) io.Writer {
+		if strings.HasPrefix(v, "test-") {
+			return &rule
+		}
+		return nil
+	})
+	r.Route("test-pinned", &acme)
+	r.Route("7", &acme)
+	SetOutput(r)
+	defer ResetOutput()
+
+	l := NewLogger("route", false)
+	for _, c := range []struct {
+		name string
+		fs   []Field
+		dest *bytes.Buffer
+	}{
+		{"no field", nil, &fallback},
+		{"route", []Field{Str("tenant", "acme")}, &acme},
+		{"removed route", []Field{Str("tenant", "globex")}, &fallback},
+		{"unrouted", []Field{Str("tenant", "initech")}, &fallback},
+		{"rule", []Field{Str("tenant", "test-1")}, &rule},
+		{"route before rule", []Field{Str("tenant", "test-pinned")}, &acme},
+		{"number", []Field{Int("tenant", 7)}, &acme},
+		{"last value", []Field{Str("tenant", "initech"), Str("tenant", "acme")}, &acme},
+		{"other key", []Field{Str("tenant_id", "acme")}, &fallback},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			for _, b := range []*bytes.Buffer{&fallback, &acme, &globex, &rule} {
+				b.Reset()
+			}
+			l.Log(InfoLevel, c.name, c.fs...)
+			for _, b := range []*bytes.Buffer{&fallback, &acme, &globex, &rule} {
+				got := strings.Contains(b.String(), c.name)
+				if got != (b == c.dest) {
+					t.Errorf("entry written = %v to %q", got, b.String())
+				}
+			}
+		})
+	}
+
+	fallback.Reset()
+	if _, err := r.Write([]byte("direct\n")); err != nil || fallback.String() != "direct\n" {
+		t.Errorf("Write = %v with fallback %q", err, fallback.String())
+	}
+}
+
+func TestRouterWithField(t *testing.T) {
+	var fallback, acme bytes.Buffer
+	r := NewRouter("tenant", &fallback)
+	r.Route("acme", &acme)
+	SetOutput(r)
+	defer ResetOutput()
+
+	NewLogger("route", false).WithField("tenant", "acme").Info("billing run")
+	if got, want := acme.String(), "route   |  billing run tenant=acme\n"; got != want || fallback.String() != "" {
+		t.Errorf("routed %q and fell back %q, want %q", got, fallback.String(), want)
+	}
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// RuntimeStats logs memory and GC stats with the default logger every
+// interval until ctx is done. See Logger.RuntimeStats.
+func RuntimeStats(ctx context.Context, interval time.Duration) {
+	defaultLogger.RuntimeStats(ctx, interval)
+}
+
+// RuntimeStats starts logging a snapshot of the heap size, goroutine
+// count and GC pauses at the debug level every interval, until ctx is
+// done, for a rough view of a process's health where there is no
+// metrics agent. It returns immediately.
+//
+// Reading the stats briefly stops the world, so keep the interval to
+// seconds or more. An interval of zero or less logs nothing.
+func (l *Logger) RuntimeStats(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		var last uint32
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			if !l.enabled(DebugLevel) {
+				continue
+			}
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			fs := []Field{
+				Uint64("heap_alloc", m.HeapAlloc),
+				Uint64("heap_sys", m.HeapSys),
+				Uint64("heap_objects", m.HeapObjects),
+				Int("goroutines", runtime.NumGoroutine()),
+				Int64("gc_count", int64(m.NumGC)),
+				Int64("gc_since_last", int64(m.NumGC-last)),
+				Dur("gc_pause_total", time.Duration(m.PauseTotalNs)),
+			}
+			if m.NumGC > 0 {
+				fs = append(fs, Dur("gc_pause_last", time.Duration(m.PauseNs[(m.NumGC+255)%256])))
+			}
+			last = m.NumGC
+			l.writeFields(DebugLevel, "runtime stats", fs)
+		}
+	}()
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	defer ResetOutput()
+	l := NewLogger("stats", false)
+	l.SetDebug(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A ticker panics with a non-positive interval, which would take
+	// down the process from the background goroutine.
+	l.RuntimeStats(ctx, 0)
+	l.RuntimeStats(ctx, -time.Second)
+
+	l.RuntimeStats(ctx, time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), "runtime stats") {
+		if time.Now().After(deadline) {
+			t.Fatal("no runtime stats logged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,153 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SuppressedKey is the field key for the number of entries dropped by
+// error sampling since the last one logged.
+const SuppressedKey = "suppressed"
+
+// maxSampleKeys bounds the memory used to track repeated errors.
+const maxSampleKeys = 10000
+
+type samplePolicy struct {
+	first    int
+	interval time.Duration
+}
+
+type sampleState struct {
+	start      time.Time
+	count      int
+	suppressed int
+	// prefix and message are from the last dropped entry, for
+	// FlushSampling.
+	prefix, message string
+}
+
+var sampling = struct {
+	sync.Mutex
+	policy *samplePolicy
+	perKey map[string]samplePolicy
+	state  map[string]*sampleState
+	// enabled is set with the lock held, and read without it so error
+	// entries don't take the lock while sampling is off.
+	enabled atomic.Bool
+}{
+	perKey: map[string]samplePolicy{},
+	state:  map[string]*sampleState{},
+}
+
+// SetErrorSampling limits repeated error entries, so an outage that
+// logs the same error in a loop doesn't flood the logs. Entries at the
+// error level with the same code, see WithCode, or the same message if
+// they have no code, are logged for the first first occurrences in each
+// interval and dropped after that. The first entry logged after the
+// interval has a suppressed field with the number dropped.
+//
+// A first of 0 or less turns sampling off, which is the default.
+func SetErrorSampling(first int, interval time.Duration) {
+	sampling.Lock()
+	defer sampling.Unlock()
+	if first <= 0 {
+		sampling.policy = nil
+	} else {
+		sampling.policy = &samplePolicy{first, interval}
+	}
+	sampling.enabled.Store(sampling.policy != nil || len(sampling.perKey) > 0)
+}
+
+// SetErrorSamplingFor sets the sampling for one code or message,
+// overriding SetErrorSampling. A first of 0 or less logs every entry
+// with that key.
+func SetErrorSamplingFor(key string, first int, interval time.Duration) {
+	sampling.Lock()
+	defer sampling.Unlock()
+	sampling.perKey[key] = samplePolicy{first, interval}
+	sampling.enabled.Store(true)
+}
+
+// FlushSampling logs a summary for every key with dropped entries, so
+// counts are not lost when the errors stop, such as at shutdown.
+func FlushSampling() {
+	sampling.Lock()
+	var summaries []*entry
+	for _, st := range sampling.state {
+		if st.suppressed == 0 {
+			continue
+		}
+		summaries = append(summaries, &entry{
+			time:    time.Now(),
+			level:   ErrorLevel,
+			prefix:  st.prefix,
+			message: fmt.Sprintf("%s (repeated)", st.message),
+			fields:  []Field{Any(SuppressedKey, st.suppressed)},
+		})
+		st.suppressed = 0
+	}
+	sampling.Unlock()
+	for _, e := range summaries {
+		runHooks(e)
+		writeAll(e)
+	}
+}
+
+// sample reports whether e should be logged, adding the suppressed
+// count to it if entries were dropped before it.
+func sample(e *entry) bool {
+	if e.level != ErrorLevel || !sampling.enabled.Load() {
+		return true
+	}
+	sampling.Lock()
+	defer sampling.Unlock()
+	key := sampleKey(e)
+	p, ok := sampling.perKey[key]
+	if !ok {
+		if sampling.policy == nil {
+			return true
+		}
+		p = *sampling.policy
+	}
+	if p.first <= 0 {
+		return true
+	}
+	st := sampling.state[key]
+	now := e.time
+	if st == nil {
+		if len(sampling.state) >= maxSampleKeys {
+			sampling.state = map[string]*sampleState{}
+		}
+		st = &sampleState{start: now}
+		sampling.state[key] = st
+	}
+	if now.Sub(st.start) >= p.interval {
+		st.start = now
+		st.count = 0
+	}
+	st.count++
+	if st.count > p.first {
+		st.suppressed++
+		st.prefix, st.message = e.prefix, e.message
+		return false
+	}
+	if st.suppressed > 0 {
+		fs := make([]Field, 0, len(e.fields)+1)
+		e.fields = append(append(fs, e.fields...), Any(SuppressedKey, st.suppressed))
+		st.suppressed = 0
+	}
+	return true
+}
+
+// sampleKey returns the entry's code, or its message if it has none.
+// As with dedupFields, the last code field is the one that counts.
+func sampleKey(e *entry) string {
+	for i := len(e.fields) - 1; i >= 0; i-- {
+		if e.fields[i].key == CodeKey {
+			return e.fields[i].stringValue()
+		}
+	}
+	return e.message
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetSampling turns error sampling off and forgets all keys when the
+// test ends.
+func resetSampling(t *testing.T) {
+	t.Cleanup(func() {
+		sampling.Lock()
+		defer sampling.Unlock()
+		sampling.policy = nil
+		sampling.perKey = map[string]samplePolicy{}
+		sampling.state = map[string]*sampleState{}
+		sampling.enabled.Store(false)
+	})
+}
+
+func TestErrorSamplingWindow(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	resetSampling(t)
+	SetErrorSampling(2, time.Minute)
+
+	l := NewLogger("db", false)
+	start := time.Now()
+	for _, c := range []struct {
+		after time.Duration
+		msg   string
+	}{
+		{0, "conn refused"},
+		{time.Second, "conn refused"},
+		{2 * time.Second, "conn refused"}, // dropped
+		{3 * time.Second, "timeout"},      // another key
+		{4 * time.Second, "conn refused"}, // dropped
+		{61 * time.Second, "conn refused"},
+		{62 * time.Second, "conn refused"},
+		{63 * time.Second, "conn refused"}, // dropped
+	} {
+		l.LogAt(start.Add(c.after), ErrorLevel, c.msg)
+	}
+	l.Info("conn refused")
+	l.Info("conn refused")
+	l.Info("conn refused")
+
+	want := strings.Join([]string{
+		"db      |  conn refused",
+		"db      |  conn refused",
+		"db      |  timeout",
+		"db      |  conn refused suppressed=2",
+		"db      |  conn refused",
+		"db      |  conn refused",
+		"db      |  conn refused",
+		"db      |  conn refused",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestErrorSamplingFlush(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	resetSampling(t)
+	SetErrorSampling(1, time.Hour)
+
+	l := NewLogger("db", false)
+	for i := 0; i < 4; i++ {
+		l.Error("conn refused")
+	}
+	FlushSampling()
+	FlushSampling()
+	want := "db      |  conn refused\ndb      |  conn refused (repeated) suppressed=3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestErrorSamplingPerKey(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	resetSampling(t)
+	SetErrorSampling(1, time.Hour)
+	SetErrorSamplingFor("E_LOUD", 0, 0)
+	SetErrorSamplingFor("E2", 2, time.Hour)
+
+	l := NewLogger("api", false)
+	for i := 0; i < 3; i++ {
+		l.WithCode("E_LOUD").Error("always")
+		// Logged as E2, so sampled as E2.
+		l.WithCode("E1").With(Str(CodeKey, "E2")).Error("overridden")
+	}
+	out := buf.String()
+	if n := strings.Count(out, "always"); n != 3 {
+		t.Errorf("logged %d entries with an unsampled code, want 3", n)
+	}
+	if n := strings.Count(out, "overridden code=E2"); n != 2 {
+		t.Errorf("logged %d E2 entries, want 2 from E2's policy: %q", n, out)
+	}
+}
+
+func TestErrorSamplingOffSkipsLock(t *testing.T) {
+	e := &entry{level: ErrorLevel, message: "m"}
+	// With sampling off, sample must not wait for the lock.
+	sampling.Lock()
+	defer sampling.Unlock()
+	if !sample(e) {
+		t.Error("sample dropped an entry with sampling off")
+	}
+}
@@ -0,0 +1,219 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FieldType is a type a Schema allows for a field.
+type FieldType string
+
+// The field types. FieldFloat also allows integers.
+const (
+	FieldString   FieldType = "string"
+	FieldInt      FieldType = "int"
+	FieldFloat    FieldType = "float"
+	FieldBool     FieldType = "bool"
+	FieldDuration FieldType = "duration"
+	FieldTime     FieldType = "time"
+)
+
+// Schema is a contract for the fields of structured entries, such as
+// one agreed with the consumers of the logs. See SetSchema.
+type Schema struct {
+	// Required are the keys every entry must have.
+	Required []string
+	// Types are the types allowed for each key. Keys not in Types can
+	// have any type.
+	Types map[string][]FieldType
+	// Strict rejects keys that are not in Types.
+	Strict bool
+	// OnViolation is called with each entry that breaks the schema.
+	// If nil, a warning is logged, once per call site.
+	OnViolation func(err *SchemaError)
+}
+
+// SchemaError describes how an entry breaks a Schema.
+type SchemaError struct {
+	// Message is the entry's message.
+	Message string
+	// Caller is the file and line that logged the entry, if known.
+	Caller string
+	// Problems are the missing keys, keys of the wrong type and, for
+	// strict schemas, unknown keys.
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("log: entry %q breaks the schema: %s", e.Message, strings.Join(e.Problems, ", "))
+}
+
+type compiledSchema struct {
+	required []string
+	types    map[string]map[FieldType]bool
+	strict   bool
+	onError  func(*SchemaError)
+}
+
+var (
+	// schema holds the *compiledSchema set with SetSchema.
+	schema      atomic.Value
+	schemaCheck = boolFlag(testBinary)
+
+	schemaWarned = struct {
+		sync.Mutex
+		sites map[string]bool
+	}{sites: map[string]bool{}}
+)
+
+func boolFlag(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// SetSchema sets the schema entries are checked against when schema
+// checks are enabled, see SetSchemaCheck. Entries are checked with all
+// their fields, after hooks have run. A nil s removes the schema.
+func SetSchema(s *Schema) {
+	if s == nil {
+		schema.Store((*compiledSchema)(nil))
+		return
+	}
+	c := &compiledSchema{
+		required: append([]string(nil), s.Required...),
+		types:    make(map[string]map[FieldType]bool, len(s.Types)),
+		strict:   s.Strict,
+		onError:  s.OnViolation,
+	}
+	for k, ts := range s.Types {
+		c.types[k] = map[FieldType]bool{}
+		for _, t := range ts {
+			c.types[k][t] = true
+		}
+	}
+	schema.Store(c)
+}
+
+// SetSchemaCheck enables or disables checking entries against the
+// schema. Checks cost an allocation or two per entry, so they are
+// meant for development and tests: they are on under go test and in
+// the Development and Kube profiles, and off otherwise.
+func SetSchemaCheck(enabled bool) {
+	atomic.StoreInt32(&schemaCheck, boolFlag(enabled))
+}
+
+// checkSchema reports e if it breaks the schema.
+func checkSchema(e *entry) {
+	if atomic.LoadInt32(&schemaCheck) == 0 {
+		return
+	}
+	s, _ := schema.Load().(*compiledSchema)
+	if s == nil {
+		return
+	}
+	problems := s.check(e.fields)
+	if len(problems) == 0 {
+		return
+	}
+	err := &SchemaError{Message: e.message, Caller: e.caller, Problems: problems}
+	if err.Caller == "" {
+		err.Caller = getCaller()
+	}
+	if s.onError != nil {
+		s.onError(err)
+		return
+	}
+	schemaWarning(err)
+}
+
+func (s *compiledSchema) check(fs []Field) []string {
+	var problems []string
+	for _, k := range s.required {
+		found := false
+		for _, f := range fs {
+			if f.key == k {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("missing %s", k))
+		}
+	}
+	for _, f := range fs {
+		ts, ok := s.types[f.key]
+		if !ok {
+			if s.strict {
+				problems = append(problems, fmt.Sprintf("unknown key %s", f.key))
+			}
+			continue
+		}
+		t := fieldType(f)
+		if !ts[t] && !(t == FieldInt && ts[FieldFloat]) {
+			problems = append(problems, fmt.Sprintf("%s is %s", f.key, describeType(t, f)))
+		}
+	}
+	return problems
+}
+
+// fieldType returns the schema type of f's value, or "" if it has none.
+func fieldType(f Field) FieldType {
+	switch f.kind {
+	case stringKind:
+		return FieldString
+	case intKind, uintKind:
+		return FieldInt
+	case floatKind:
+		return FieldFloat
+	case boolKind:
+		return FieldBool
+	case durationKind:
+		return FieldDuration
+	}
+	switch f.Value().(type) {
+	case string:
+		return FieldString
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return FieldInt
+	case float32, float64:
+		return FieldFloat
+	case bool:
+		return FieldBool
+	case time.Duration:
+		return FieldDuration
+	case time.Time:
+		return FieldTime
+	}
+	return ""
+}
+
+func describeType(t FieldType, f Field) string {
+	if t == "" {
+		return fmt.Sprintf("%T", f.Value())
+	}
+	return string(t)
+}
+
+// schemaWarning logs err once per call site. Like FlushSampling, it
+// writes the warning directly, so it is not itself checked.
+func schemaWarning(err *SchemaError) {
+	schemaWarned.Lock()
+	warned := schemaWarned.sites[err.Caller]
+	schemaWarned.sites[err.Caller] = true
+	schemaWarned.Unlock()
+	if warned || !defaultLogger.levelEnabled(WarnLevel) {
+		return
+	}
+	msg := err.Error()
+	if err.Caller != "" {
+		msg += " at " + err.Caller
+	}
+	e := defaultLogger.newEntry(WarnLevel, msg, nil)
+	writeAll(e)
+	e.free()
+}
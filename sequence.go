@@ -0,0 +1,52 @@
+package log
+
+import "sync/atomic"
+
+// SequenceKey is the field key for entry sequence numbers.
+const SequenceKey = "seq"
+
+var (
+	sequenceEnabled int32
+	// sequence numbers entries for SetSequence.
+	sequence atomic.Uint64
+)
+
+// SetSequence enables or disables adding a seq field to every entry,
+// numbered from 1 across the process, so consumers can spot entries
+// lost or reordered on the way, such as through a UDP sink. Entries
+// dropped by sampling or suppression are not numbered, so gaps mean
+// loss after the entry was logged. The seq field replaces a field of
+// the same name from the logger or the logging call; see Logger.With.
+//
+// Loggers returned by WithSequence use their own numbering instead.
+func SetSequence(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&sequenceEnabled, v)
+}
+
+// WithSequence returns a copy of the logger that adds a seq field to
+// every entry, numbered from 1. The numbering is shared with loggers
+// derived from the copy, and is separate from SetSequence's.
+func (l *Logger) WithSequence() *Logger {
+	c := l.derive()
+	c.seq = new(atomic.Uint64)
+	return c
+}
+
+// numberEntry adds the seq field to e if numbering applies.
+func numberEntry(e *entry) {
+	var n uint64
+	switch {
+	case e.seq != nil:
+		n = e.seq.Add(1)
+	case atomic.LoadInt32(&sequenceEnabled) != 0:
+		n = sequence.Add(1)
+	default:
+		return
+	}
+	fs := make([]Field, 0, len(e.fields)+1)
+	e.fields = append(append(fs, e.fields...), Uint64(SequenceKey, n))
+}
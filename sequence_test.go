@@ -0,0 +1,36 @@
+package log
+
+import "testing"
+
+func TestSequenceOverridesField(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	SetSequence(true)
+	sequence.Store(0)
+	t.Cleanup(func() { SetSequence(false) })
+
+	l := NewLogger("seq", false).With(Str(SequenceKey, "mine"))
+	l.Info("one")
+	l.Log(InfoLevel, "two", Str(SequenceKey, "call"))
+	own := NewLogger("own", false).WithSequence()
+	own.Info("three")
+
+	want := "seq     |  one seq=1\nseq     |  two seq=2\nown     |  three seq=1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestSequenceHookOverrides(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	AddHook(func(h *HookEntry) { h.AddField(SequenceKey, "hook") })
+	t.Cleanup(ClearHooks)
+
+	NewLogger("seq", false).WithSequence().Info("one")
+	if got, want := buf.String(), "seq     |  one seq=hook\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
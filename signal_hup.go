@@ -0,0 +1,12 @@
+//go:build unix || windows
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+// refreshSignals are the signals RefreshEnvOnSignal listens for by
+// default.
+var refreshSignals = []os.Signal{syscall.SIGHUP}
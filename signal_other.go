@@ -0,0 +1,9 @@
+//go:build !(unix || windows)
+
+package log
+
+import "os"
+
+// refreshSignals is empty where there is no SIGHUP, so
+// RefreshEnvOnSignal only listens for the signals it is given.
+var refreshSignals []os.Signal
@@ -0,0 +1,154 @@
+package log
+
+import (
+	"bytes"
+	"time"
+)
+
+// Entry is a log entry as seen by an Encoder or Sink.
+//
+// Entry, Encoder and Sink are the extension points of the package and
+// are kept compatible: fields may be added to Entry, but existing ones
+// keep their meaning, and the interfaces will not change. The
+// testdata/extension directory of the repository has example
+// implementations that only use the exported API, and are tested with
+// the package.
+type Entry struct {
+	Time   time.Time
+	Level  Level
+	Prefix string
+	// Caller is the file and line that logged the entry, if callers
+	// are enabled.
+	Caller  string
+	Message string
+	// Fields are in the order they are rendered. Lazy and provider
+	// fields have already been evaluated.
+	Fields []Field
+	// Groups are the titles of the open groups, outermost first.
+	Groups []string
+	// Debug is set if the logger had debug enabled, which adds the
+	// level column to the text formats.
+	Debug bool
+}
+
+func (e *entry) export() *Entry {
+	return &Entry{
+		Time:    e.time,
+		Level:   e.level,
+		Prefix:  e.prefix,
+		Caller:  e.caller,
+		Message: e.message,
+		Fields:  e.fields,
+		Groups:  e.groups,
+		Debug:   e.debug,
+	}
+}
+
+// Encoder renders entries for SetEncoder. Encode appends the
+// rendering of e, ending with a newline, to b. It is called with the
+// output locked, so it need not be safe for concurrent use, and must
+// not keep e or its fields after returning.
+//
+//	type tabEncoder struct{}
+//
+//	func (tabEncoder) Encode(b *bytes.Buffer, e *log.Entry) {
+//		fmt.Fprintf(b, "%s\t%s\t%s\n", e.Level, e.Prefix, e.Message)
+//	}
+type Encoder interface {
+	Encode(b *bytes.Buffer, e *Entry)
+}
+
+// SetEncoder renders all output with enc instead of one of the
+// built in formats. SetFormat replaces it again.
+func SetEncoder(enc Encoder) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.custom = enc
+	out.enc = out.newEncoder()
+}
+
+type customEncoder struct {
+	enc Encoder
+}
+
+func (c customEncoder) encode(b *bytes.Buffer, e *entry) {
+	c.enc.Encode(b, e.export())
+}
+
+// FormatEncoder returns the encoder for one of the built in formats,
+// without color, for outputs that take an Encoder. Unknown formats get
+// the text encoder.
+func FormatEncoder(f Format) Encoder {
+	o := &output{format: f, facility: 1}
+	return builtinEncoder{o.newEncoder()}
+}
+
+// builtinEncoder exports a built in encoder. output unwraps it to
+// encode entries directly.
+type builtinEncoder struct {
+	enc encoder
+}
+
+func (b builtinEncoder) Encode(buf *bytes.Buffer, e *Entry) {
+	b.enc.encode(buf, &entry{
+		time:    e.Time,
+		level:   e.Level,
+		prefix:  e.Prefix,
+		caller:  e.Caller,
+		message: e.Message,
+		fields:  e.Fields,
+		groups:  e.Groups,
+		debug:   e.Debug,
+	})
+}
+
+// internalEncoder returns the encoder output uses for enc.
+func internalEncoder(enc Encoder) encoder {
+	if b, ok := enc.(builtinEncoder); ok {
+		return b.enc
+	}
+	return customEncoder{enc}
+}
+
+// Sink is a destination that needs the entry, not only its rendering,
+// such as one that sends fields to a service's own API. WriteEntry is
+// called with the entry and its encoding, with the output locked, and
+// must not keep either after returning. Errors are handled as write
+// errors; see SetErrorHandler.
+type Sink interface {
+	WriteEntry(e *Entry, encoded []byte) error
+}
+
+// SetSink sets the destination for all log output, including Die, to
+// s.
+func SetSink(s Sink) {
+	out.setWriter(sinkWriter{s}, nil)
+}
+
+// AddSink sends all log output to s, encoded with enc, as well as to
+// the main output, until remove is called. A nil enc encodes JSON. See
+// AddOutput.
+func AddSink(s Sink, enc Encoder) (remove func()) {
+	if enc == nil {
+		enc = FormatEncoder(FormatJSON)
+	}
+	return AddOutputEncoder(sinkWriter{s}, enc)
+}
+
+// sinkWriter adapts a Sink to the writers output uses.
+type sinkWriter struct {
+	s Sink
+}
+
+func (w sinkWriter) writeEntry(e *entry, p []byte) error {
+	return w.s.WriteEntry(e.export(), p)
+}
+
+// Write passes p to the sink with an empty entry, for writes that do
+// not come from an entry.
+func (w sinkWriter) Write(p []byte) (int, error) {
+	if err := w.s.WriteEntry(&Entry{}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
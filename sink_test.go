@@ -0,0 +1,81 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+type panicEncoder struct{}
+
+func (panicEncoder) Encode(b *bytes.Buffer, e *Entry) {
+	panic("encoder bug")
+}
+
+type panicSink struct{}
+
+func (panicSink) WriteEntry(e *Entry, encoded []byte) error {
+	panic("sink bug")
+}
+
+// logRecovered logs an entry, returning the value it panicked with.
+func logRecovered(l *Logger) (r interface{}) {
+	defer func() { r = recover() }()
+	l.Info("boom")
+	return nil
+}
+
+func TestPanickingExtensionUnlocks(t *testing.T) {
+	var buf syncBuffer
+	defer func() {
+		SetFormat(FormatText)
+		ResetOutput()
+	}()
+	l := NewLogger("panic", false)
+	for _, set := range []func(){
+		func() { SetOutput(&buf); SetEncoder(panicEncoder{}) },
+		func() { SetFormat(FormatText); SetSink(panicSink{}) },
+	} {
+		set()
+		if r := logRecovered(l); r == nil {
+			t.Fatal("expected the extension to panic")
+		}
+		SetOutput(&buf)
+		SetFormat(FormatText)
+		done := make(chan struct{})
+		go func() {
+			l.Info("after panic")
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("output still locked after a panic")
+		}
+	}
+}
+
+// TestExtensionExamples builds and tests the example extensions in
+// testdata, which only use the exported API.
+func TestExtensionExamples(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	cmd := exec.Command(gobin, "test", "./testdata/extension")
+	cmd.Env = os.Environ()
+	out, err := cmd.CombinedOutput()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		t.Fatalf("example extensions fail:\n%s", out)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,196 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// SlogHandler returns a slog.Handler that logs records through l, so
+// code using log/slog shares this package's outputs, format and
+// settings. If l is nil, the default "main" logger is used.
+//
+//	slog.SetDefault(slog.New(log.SlogHandler(nil)))
+//
+// slog levels below info map to DebugLevel, then InfoLevel, WarnLevel
+// and ErrorLevel. Attributes become fields, with the names of groups
+// joined to their keys by ".".
+func SlogHandler(l *Logger) slog.Handler {
+	if l == nil {
+		l = defaultLogger
+	}
+	return &slogHandler{l: l}
+}
+
+type slogHandler struct {
+	l     *Logger
+	attrs []Field
+	group string
+}
+
+func (h *slogHandler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return h.l.enabled(fromSlogLevel(lvl))
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	fs := make([]Field, 0, len(h.attrs)+r.NumAttrs())
+	fs = append(fs, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		fs = appendSlogAttr(fs, h.group, a)
+		return true
+	})
+	e := h.l.newEntry(fromSlogLevel(r.Level), r.Message, fs)
+	if !r.Time.IsZero() {
+		e.time = r.Time
+	}
+	if e.caller != "" && r.PC != 0 {
+		e.caller = resolveCaller(r.PC)
+	}
+	emit(e)
+	e.free()
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = make([]Field, 0, len(h.attrs)+len(as))
+	c.attrs = append(c.attrs, h.attrs...)
+	for _, a := range as {
+		c.attrs = appendSlogAttr(c.attrs, h.group, a)
+	}
+	return &c
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.group = slogKey(h.group, name)
+	return &c
+}
+
+func appendSlogAttr(fs []Field, group string, a slog.Attr) []Field {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			group = slogKey(group, a.Key)
+		}
+		for _, ga := range v.Group() {
+			fs = appendSlogAttr(fs, group, ga)
+		}
+		return fs
+	}
+	if a.Key == "" {
+		return fs
+	}
+	return append(fs, Any(slogKey(group, a.Key), v.Any()))
+}
+
+func slogKey(group, key string) string {
+	if group == "" {
+		return key
+	}
+	return group + "." + key
+}
+
+func fromSlogLevel(lvl slog.Level) Level {
+	switch {
+	case lvl < slog.LevelInfo:
+		return DebugLevel
+	case lvl < slog.LevelWarn:
+		return InfoLevel
+	case lvl < slog.LevelError:
+		return WarnLevel
+	}
+	return ErrorLevel
+}
+
+func toSlogLevel(lvl Level) slog.Level {
+	switch lvl {
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	}
+	return slog.LevelError + 4
+}
+
+var slogDelegation int32
+
+// SetSlogDelegation sets whether entries are handed to slog's default
+// handler, instead of being written to the main output, when the
+// program has set one with slog.SetDefault. A codebase that uses both
+// packages then gets one output format, slog's. Hooks, sampling and
+// added outputs still apply. It is off by default.
+//
+// Nothing is delegated while slog's default is its built in handler,
+// which writes through the standard library log package, or a
+// SlogHandler, which would send entries back here. To go the other way
+// and have slog write through this package, use SlogHandler.
+//
+// The logger's prefix is passed as a prefix attribute, fatal entries
+// are logged at slog.LevelError+4 and the caller is passed as a caller
+// attribute.
+func SetSlogDelegation(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&slogDelegation, v)
+}
+
+// slogDelegate returns slog's default handler if entries should be
+// delegated to it.
+func slogDelegate() slog.Handler {
+	if atomic.LoadInt32(&slogDelegation) == 0 {
+		return nil
+	}
+	h := slog.Default().Handler()
+	if _, ok := h.(*slogHandler); ok {
+		return nil
+	}
+	// The built in handler is unexported, so go by its type name.
+	if fmt.Sprintf("%T", h) == "*slog.defaultHandler" {
+		return nil
+	}
+	return h
+}
+
+// writeSlog hands e to h, reporting whether it was handled.
+func writeSlog(h slog.Handler, e *entry) bool {
+	lvl := toSlogLevel(e.level)
+	ctx := context.Background()
+	if !h.Enabled(ctx, lvl) {
+		return true
+	}
+	t := e.time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	r := slog.NewRecord(t, lvl, e.message, 0)
+	if e.prefix != "" {
+		r.AddAttrs(slog.String("prefix", e.prefix))
+	}
+	if e.caller != "" {
+		r.AddAttrs(slog.String("caller", e.caller))
+	}
+	if len(e.groups) > 0 {
+		r.AddAttrs(slog.String("group", strings.Join(e.groups, "/")))
+	}
+	for _, f := range e.fields {
+		r.AddAttrs(slog.Any(f.key, f.Value()))
+	}
+	if err := h.Handle(ctx, r); err != nil {
+		reportError(&SinkError{Sink: fmt.Sprintf("slog handler %T", h), Err: err})
+		return false
+	}
+	return true
+}
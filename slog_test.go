@@ -0,0 +1,123 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type tokenValue string
+
+func (v tokenValue) LogValue() slog.Value { return slog.StringValue("redacted") }
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	l := NewLogger("slog", false)
+	l.SetDebug(true)
+	sl := slog.New(SlogHandler(l))
+
+	for _, c := range []struct {
+		name string
+		log  func()
+		want string
+	}{
+		{"info", func() { sl.Info("hello", "user", "bob", "n", 3) },
+			"NFO  |  slog    |  hello user=bob n=3"},
+		{"debug", func() { sl.Debug("details") }, "DBG  |  slog    |  details"},
+		{"below debug", func() { sl.Log(context.Background(), slog.Level(-8), "trace") }, "DBG  |  slog    |  trace"},
+		{"between levels", func() { sl.Log(context.Background(), slog.LevelInfo+2, "notice") }, "NFO  |  slog    |  notice"},
+		{"warn", func() { sl.Warn("careful") }, "WRN  |  slog    |  careful"},
+		{"error", func() { sl.Error("failed", "err", "boom") }, "ERR  |  slog    |  failed err=boom"},
+		{"above error", func() { sl.Log(context.Background(), slog.LevelError+4, "fatal") }, "ERR  |  slog    |  fatal"},
+		{"attrs and groups", func() {
+			sl.With("a", 1).WithGroup("req").With("id", 7).Info("m",
+				"k", "v",
+				slog.Group("sub", "x", 1),
+				slog.Group("", "inline", 2),
+				slog.Attr{},
+				slog.Group("empty"))
+		}, "NFO  |  slog    |  m a=1 req.id=7 req.k=v req.sub.x=1 req.inline=2"},
+		{"empty group name", func() { sl.WithGroup("").Info("m", "k", 1) }, "NFO  |  slog    |  m k=1"},
+		{"LogValuer", func() { sl.Info("login", "token", tokenValue("secret")) },
+			"NFO  |  slog    |  login token=redacted"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			buf.Reset()
+			c.log()
+			got := buf.String()
+			// Drop the caller column, whose line numbers would make the
+			// test brittle.
+			if parts := strings.SplitN(got, "  |  ", 4); len(parts) == 4 {
+				got = strings.Join([]string{parts[0], parts[1], parts[3]}, "  |  ")
+			}
+			if got != c.want+"\n" {
+				t.Errorf("got %q, want %q", got, c.want+"\n")
+			}
+		})
+	}
+}
+
+func TestSlogHandlerCaller(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	l := NewLogger("slog", false)
+	l.SetDebug(true)
+	slog.New(SlogHandler(l)).Info("here")
+	if !strings.Contains(buf.String(), "slog_test.go:") {
+		t.Errorf("output = %q, want the caller of slog", buf.String())
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	keepSettings(t)
+	h := SlogHandler(NewLogger("slog", false))
+	ctx := context.Background()
+	if h.Enabled(ctx, slog.LevelDebug) {
+		t.Error("debug enabled on a logger without debug")
+	}
+	SetLevel(WarnLevel)
+	if h.Enabled(ctx, slog.LevelInfo) || !h.Enabled(ctx, slog.LevelWarn) {
+		t.Error("Enabled does not follow the level")
+	}
+}
+
+func TestSlogDelegation(t *testing.T) {
+	def := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(def) })
+	var sbuf, lbuf bytes.Buffer
+	SetOutput(&lbuf)
+	t.Cleanup(ResetOutput)
+	SetSlogDelegation(true)
+	t.Cleanup(func() { SetSlogDelegation(false) })
+
+	noTime := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		return a
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(&sbuf, &slog.HandlerOptions{ReplaceAttr: noTime})))
+	l := NewLogger("api", false)
+	defer l.Group("sync")()
+	sbuf.Reset()
+	l.Log(WarnLevel, "slow", Int("ms", 900))
+	if want := "level=WARN msg=slow prefix=api group=sync ms=900\n"; sbuf.String() != want {
+		t.Errorf("slog got %q, want %q", sbuf.String(), want)
+	}
+	if lbuf.Len() != 0 {
+		t.Errorf("delegated entry also written to the output: %q", lbuf.String())
+	}
+
+	// A SlogHandler default would send entries back here.
+	slog.SetDefault(slog.New(SlogHandler(nil)))
+	lbuf.Reset()
+	l.Info("direct")
+	if !strings.Contains(lbuf.String(), "direct") {
+		t.Errorf("output = %q, want the entry written directly", lbuf.String())
+	}
+}
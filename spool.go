@@ -0,0 +1,253 @@
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrSpoolFull is returned by SpoolWriter when an entry could not be
+// written and there is no room left to spool it.
+var ErrSpoolFull = errors.New("log: spool full")
+
+// DefaultSpoolRetry is how often a SpoolWriter retries a failing
+// writer by default.
+const DefaultSpoolRetry = time.Second
+
+// SpoolWriter wraps a writer that can fail transiently, such as a
+// network connection, and appends entries that fail to be written to a
+// spool file. Spooled entries are replayed, in order, before new ones
+// once the writer works again.
+//
+// Each Write is treated as one entry and replayed with a single Write,
+// as output does. Entries left in the spool file when the process
+// exits are replayed by the next SpoolWriter using the same path.
+//
+// The position of the last entry replayed successfully is recorded in
+// a checkpoint file next to the spool, the spool path with ".ckpt"
+// added, so a restart part way through a replay picks up where it left
+// off instead of sending entries twice. At most the entry being
+// replayed when the process died is sent again.
+type SpoolWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	f     *os.File
+	ckpt  *os.File
+	max   int64
+	retry time.Duration
+	// size is the length of the spool file and off the position of
+	// the next entry to replay.
+	size, off int64
+	lastTry   time.Time
+	spooled   uint64
+	// queued is the number of entries waiting to be replayed.
+	queued int
+}
+
+// NewSpoolWriter returns a SpoolWriter writing to w and spooling to
+// the file at path, which holds at most maxBytes. A maxBytes of 0 or
+// less means no limit.
+func NewSpoolWriter(w io.Writer, path string, maxBytes int64) (*SpoolWriter, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	ckpt, err := os.OpenFile(path+".ckpt", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s := &SpoolWriter{
+		w:     w,
+		f:     f,
+		ckpt:  ckpt,
+		max:   maxBytes,
+		retry: DefaultSpoolRetry,
+		size:  fi.Size(),
+	}
+	var b [8]byte
+	if _, err := ckpt.ReadAt(b[:], 0); err == nil {
+		// A checkpoint past the end is left from a spool that was
+		// truncated before the checkpoint was cleared.
+		if off := int64(binary.BigEndian.Uint64(b[:])); off <= s.size {
+			s.off = off
+		}
+	}
+	s.queued = s.countRecords()
+	return s, nil
+}
+
+// countRecords returns the number of records left to replay, up to the
+// first that can't be read.
+func (s *SpoolWriter) countRecords() int {
+	var hdr [4]byte
+	n := 0
+	for off := s.off; off < s.size; n++ {
+		if _, err := s.f.ReadAt(hdr[:], off); err != nil {
+			break
+		}
+		off += int64(4 + binary.BigEndian.Uint32(hdr[:]))
+	}
+	return n
+}
+
+// SetRetry sets how long to wait after a failed write before trying
+// the writer again. Until then, entries go straight to the spool.
+func (s *SpoolWriter) SetRetry(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retry = d
+}
+
+// Write writes p to the underlying writer, or spools it if that fails
+// or there are spooled entries that could not be replayed yet. It only
+// returns an error if p could not be spooled either.
+func (s *SpoolWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending() && time.Since(s.lastTry) >= s.retry {
+		s.replay()
+	}
+	if !s.pending() && time.Since(s.lastTry) >= s.retry {
+		_, err := s.w.Write(p)
+		if err == nil {
+			return len(p), nil
+		}
+		s.lastTry = time.Now()
+		if serr := s.spool(p); serr != nil {
+			return 0, fmt.Errorf("%w: %v", serr, err)
+		}
+		return len(p), nil
+	}
+	if err := s.spool(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Replay writes spooled entries to the underlying writer now,
+// regardless of the retry interval.
+func (s *SpoolWriter) Replay() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replay()
+}
+
+// Pending returns the number of bytes of entries waiting in the spool.
+func (s *SpoolWriter) Pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size - s.off
+}
+
+func (s *SpoolWriter) queueDepth() int {
+	s.mu.Lock()
+	n, w := s.queued, s.w
+	s.mu.Unlock()
+	if q, ok := w.(queueReporter); ok {
+		n += q.queueDepth()
+	}
+	return n
+}
+
+// Spooled returns the number of entries spooled since the SpoolWriter
+// was created.
+func (s *SpoolWriter) Spooled() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spooled
+}
+
+// Close closes the spool and checkpoint files. The underlying writer
+// is not closed.
+func (s *SpoolWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.f.Close()
+	if cerr := s.ckpt.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *SpoolWriter) pending() bool {
+	return s.off < s.size
+}
+
+// spool appends p to the spool file as a length prefixed record.
+func (s *SpoolWriter) spool(p []byte) error {
+	n := int64(4 + len(p))
+	if s.max > 0 && s.size+n > s.max {
+		return ErrSpoolFull
+	}
+	rec := make([]byte, n)
+	binary.BigEndian.PutUint32(rec, uint32(len(p)))
+	copy(rec[4:], p)
+	if _, err := s.f.Write(rec); err != nil {
+		return err
+	}
+	s.size += n
+	s.spooled++
+	s.queued++
+	return nil
+}
+
+// replay writes spooled records until one fails or the spool is
+// empty, at which point the file is truncated.
+func (s *SpoolWriter) replay() error {
+	var hdr [4]byte
+	for s.pending() {
+		if _, err := s.f.ReadAt(hdr[:], s.off); err != nil {
+			return s.corrupt(err)
+		}
+		rec := make([]byte, binary.BigEndian.Uint32(hdr[:]))
+		if _, err := s.f.ReadAt(rec, s.off+4); err != nil {
+			return s.corrupt(err)
+		}
+		if _, err := s.w.Write(rec); err != nil {
+			s.lastTry = time.Now()
+			return err
+		}
+		s.off += int64(4 + len(rec))
+		s.queued--
+		if err := s.checkpoint(); err != nil {
+			return err
+		}
+	}
+	return s.reset()
+}
+
+// checkpoint records the replay position. The checkpoint is not
+// synced, so it survives the process dying but not the host.
+func (s *SpoolWriter) checkpoint() error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(s.off))
+	_, err := s.ckpt.WriteAt(b[:], 0)
+	return err
+}
+
+// corrupt discards a spool file that can't be read back, such as one
+// cut short by a crash mid write.
+func (s *SpoolWriter) corrupt(err error) error {
+	s.reset()
+	return fmt.Errorf("log: discarding unreadable spool %s: %v", s.f.Name(), err)
+}
+
+// reset empties the spool, truncating before clearing the checkpoint
+// so a crash in between can't skip entries.
+func (s *SpoolWriter) reset() error {
+	s.size, s.off, s.queued = 0, 0, 0
+	if err := s.f.Truncate(0); err != nil {
+		return err
+	}
+	return s.checkpoint()
+}
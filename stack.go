@@ -0,0 +1,53 @@
+package log
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// StackKey is the field key used by Stack.
+const StackKey = "stack"
+
+// DefaultStackDepth is the number of frames Stack captures.
+const DefaultStackDepth = 32
+
+// Stack returns a field with the stack of the calling goroutine, up to
+// DefaultStackDepth frames, one "function file:line" per line, for
+// attaching to anomalies that are worth investigating but not worth a
+// panic:
+//
+//	logger.With(log.Stack()).Warn("cache entry changed while locked")
+func Stack() Field {
+	return stackField(DefaultStackDepth, 3)
+}
+
+// StackDepth is Stack with up to depth frames.
+func StackDepth(depth int) Field {
+	return stackField(depth, 3)
+}
+
+func stackField(depth, skip int) Field {
+	if depth <= 0 {
+		depth = DefaultStackDepth
+	}
+	pcs := make([]uintptr, depth)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	for i := 0; i < depth; i++ {
+		f, more := frames.Next()
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(f.Function)
+		b.WriteByte(' ')
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		if !more {
+			break
+		}
+	}
+	return Str(StackKey, b.String())
+}
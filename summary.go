@@ -0,0 +1,123 @@
+package log
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Summary accumulates counters and fields over an operation, such as
+// a batch job, and logs them as a single entry when closed:
+//
+//	s := logger.NewSummary("sync users")
+//	defer s.Close()
+//	for _, u := range users {
+//		if err := sync(u); err != nil {
+//			s.Error(err)
+//			continue
+//		}
+//		s.Inc("synced")
+//	}
+//
+// A Summary is safe for concurrent use.
+type Summary struct {
+	mu       sync.Mutex
+	logger   *Logger
+	name     string
+	start    time.Time
+	counters map[string]int64
+	fields   map[string]interface{}
+	errors   int64
+	lastErr  error
+	closed   bool
+}
+
+// NewSummary starts a summary with the default logger.
+func NewSummary(name string) *Summary {
+	return defaultLogger.NewSummary(name)
+}
+
+// NewSummary starts a summary logged by the logger when closed.
+func (l *Logger) NewSummary(name string) *Summary {
+	return &Summary{
+		logger:   l,
+		name:     name,
+		start:    time.Now(),
+		counters: map[string]int64{},
+		fields:   map[string]interface{}{},
+	}
+}
+
+// Add adds n to the counter key.
+func (s *Summary) Add(key string, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters[key] += n
+}
+
+// Inc adds one to the counter key.
+func (s *Summary) Inc(key string) {
+	s.Add(key, 1)
+}
+
+// Set sets a field to report, replacing any previous value.
+func (s *Summary) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fields[key] = value
+}
+
+// Error counts an error. The last error is included in the summary.
+// Nil errors are ignored.
+func (s *Summary) Error(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors++
+	s.lastErr = err
+}
+
+// Close logs the summary with the duration, error count and all
+// counters and fields, in key order. It is logged at the info level,
+// or the warn level if any errors were counted. Only the first call
+// logs anything.
+func (s *Summary) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	fs := []Field{
+		Any("duration", formatDuration(time.Since(s.start))),
+		Any("errors", s.errors),
+	}
+	if s.lastErr != nil {
+		fs = append(fs, Any("last_error", s.lastErr.Error()))
+	}
+	keys := make([]string, 0, len(s.counters)+len(s.fields))
+	for k := range s.counters {
+		keys = append(keys, k)
+	}
+	for k := range s.fields {
+		if _, ok := s.counters[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v, ok := s.fields[k]; ok {
+			fs = append(fs, Any(k, v))
+		} else {
+			fs = append(fs, Any(k, s.counters[k]))
+		}
+	}
+	lvl := InfoLevel
+	if s.errors > 0 {
+		lvl = WarnLevel
+	}
+	s.mu.Unlock()
+	s.logger.with(fs...).logf(lvl, "%s done", s.name)
+}
@@ -0,0 +1,134 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sync/atomic"
+	"time"
+)
+
+// Suppression matches known noisy entries, such as those logged by
+// dependencies, to drop them or log them at a lower level.
+type Suppression struct {
+	// Message matches entries with exactly this message.
+	Message string
+	// Pattern, if set, matches entries whose message it matches,
+	// instead of Message.
+	Pattern *regexp.Regexp
+	// Expires stops the suppression after this time, so it gets
+	// looked at again. The zero time never expires.
+	Expires time.Time
+	// Drop drops matching entries. Otherwise they are logged at
+	// Level, if it is lower than their own.
+	Drop  bool
+	Level Level
+}
+
+func (s *Suppression) match(e *entry) bool {
+	if !s.Expires.IsZero() && e.time.After(s.Expires) {
+		return false
+	}
+	if s.Pattern != nil {
+		return s.Pattern.MatchString(e.message)
+	}
+	return s.Message == e.message
+}
+
+var (
+	// suppressions holds a []Suppression, replaced on change.
+	suppressions atomic.Value
+	suppressed   uint64
+)
+
+// SetSuppressions replaces the suppressions applied to every entry,
+// before sampling and hooks. The first that matches an entry applies.
+// Die entries are never suppressed. A nil slice removes them all.
+func SetSuppressions(ss []Suppression) {
+	suppressions.Store(append([]Suppression(nil), ss...))
+}
+
+// Suppressed returns the number of entries that matched a suppression.
+func Suppressed() uint64 {
+	return atomic.LoadUint64(&suppressed)
+}
+
+// LoadSuppressions reads a JSON suppression catalog, a list of:
+//
+//	{"message": "exact message", "action": "drop"}
+//	{"regexp": "^retrying .*", "action": "debug", "expires": "2025-06-30"}
+//
+// The action is "drop" or a level name to downgrade to, "drop" if
+// empty. expires is a date or an RFC 3339 time.
+func LoadSuppressions(r io.Reader) ([]Suppression, error) {
+	var cat []struct {
+		Message string `json:"message"`
+		Regexp  string `json:"regexp"`
+		Action  string `json:"action"`
+		Expires string `json:"expires"`
+	}
+	if err := json.NewDecoder(r).Decode(&cat); err != nil {
+		return nil, fmt.Errorf("log: reading suppressions: %w", err)
+	}
+	ss := make([]Suppression, len(cat))
+	for i, c := range cat {
+		s := &ss[i]
+		s.Message = c.Message
+		if c.Regexp != "" {
+			re, err := regexp.Compile(c.Regexp)
+			if err != nil {
+				return nil, fmt.Errorf("log: suppression %d: %w", i, err)
+			}
+			s.Pattern = re
+		}
+		if c.Action == "" || c.Action == "drop" {
+			s.Drop = true
+		} else {
+			lvl, err := ParseLevel(c.Action)
+			if err != nil {
+				return nil, fmt.Errorf("log: suppression %d: %w", i, err)
+			}
+			s.Level = lvl
+		}
+		if c.Expires != "" {
+			t, err := time.Parse(time.RFC3339, c.Expires)
+			if err != nil {
+				t, err = time.Parse("2006-01-02", c.Expires)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("log: suppression %d: bad expiry %q", i, c.Expires)
+			}
+			s.Expires = t
+		}
+	}
+	return ss, nil
+}
+
+// suppress applies the suppressions to e, reporting whether it should
+// still be logged.
+func suppress(e *entry) bool {
+	ss, _ := suppressions.Load().([]Suppression)
+	if len(ss) == 0 || e.level == FatalLevel {
+		return true
+	}
+	for i := range ss {
+		s := &ss[i]
+		if !s.match(e) {
+			continue
+		}
+		atomic.AddUint64(&suppressed, 1)
+		if s.Drop {
+			return false
+		}
+		if s.Level < e.level {
+			e.level = s.Level
+		}
+		// The logger's level check was for the original level.
+		if e.level == DebugLevel && !e.debug {
+			return false
+		}
+		return e.level >= GetLevel()
+	}
+	return true
+}
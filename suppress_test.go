@@ -0,0 +1,104 @@
+package log
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSuppress(t *testing.T) {
+	keepSettings(t)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	SetSuppressions([]Suppression{
+		{Message: "noisy", Drop: true},
+		{Message: "expired", Drop: true, Expires: now.Add(-time.Hour)},
+		{Message: "expiring", Drop: true, Expires: now.Add(time.Hour)},
+		{Pattern: regexp.MustCompile(`^retrying `), Level: DebugLevel},
+		{Message: "quieter", Level: WarnLevel},
+		{Message: "louder", Level: ErrorLevel},
+		{Message: "noisy", Level: WarnLevel},
+	})
+	t.Cleanup(func() { SetSuppressions(nil) })
+
+	for _, c := range []struct {
+		name    string
+		entry   entry
+		keep    bool
+		level   Level
+		counted bool
+	}{
+		{"unmatched", entry{level: ErrorLevel, message: "other"}, true, ErrorLevel, false},
+		{"dropped", entry{level: ErrorLevel, message: "noisy"}, false, ErrorLevel, true},
+		{"expired", entry{level: InfoLevel, message: "expired"}, true, InfoLevel, false},
+		{"not yet expired", entry{level: InfoLevel, message: "expiring"}, false, InfoLevel, true},
+		{"pattern to debug", entry{level: WarnLevel, message: "retrying fetch"}, false, DebugLevel, true},
+		{"pattern to debug, debug logger", entry{level: WarnLevel, message: "retrying fetch", debug: true}, true, DebugLevel, true},
+		{"pattern anchored", entry{level: WarnLevel, message: "not retrying "}, true, WarnLevel, false},
+		{"downgraded", entry{level: ErrorLevel, message: "quieter"}, true, WarnLevel, true},
+		{"never raised", entry{level: InfoLevel, message: "louder"}, true, InfoLevel, true},
+		{"fatal", entry{level: FatalLevel, message: "noisy"}, true, FatalLevel, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			e := c.entry
+			e.time = now
+			before := Suppressed()
+			keep := suppress(&e)
+			if keep != c.keep || e.level != c.level {
+				t.Errorf("suppress = %v at %v, want %v at %v", keep, e.level, c.keep, c.level)
+			}
+			if n := Suppressed() - before; (n == 1) != c.counted {
+				t.Errorf("Suppressed went up by %d, want counted = %v", n, c.counted)
+			}
+		})
+	}
+}
+
+func TestSuppressLevel(t *testing.T) {
+	keepSettings(t)
+	var buf syncBuffer
+	SetOutput(&buf)
+	t.Cleanup(ResetOutput)
+	SetSuppressions([]Suppression{{Message: "quieter", Level: InfoLevel}})
+	t.Cleanup(func() { SetSuppressions(nil) })
+	SetLevel(WarnLevel)
+
+	l := NewLogger("sup", false)
+	l.Error("quieter")
+	l.Error("kept")
+	if got, want := buf.String(), "sup     |  kept\n"; got != want {
+		t.Errorf("output = %q, want %q, as the downgraded entry is below the level", got, want)
+	}
+}
+
+func TestLoadSuppressions(t *testing.T) {
+	ss, err := LoadSuppressions(strings.NewReader(`[
+		{"message": "a"},
+		{"message": "b", "action": "drop"},
+		{"regexp": "^retrying .*", "action": "debug", "expires": "2025-06-30"},
+		{"message": "c", "action": "warning", "expires": "2025-06-30T12:00:00+02:00"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 4 || !ss[0].Drop || !ss[1].Drop || ss[2].Drop || ss[3].Drop {
+		t.Fatalf("suppressions = %+v", ss)
+	}
+	if ss[2].Pattern.String() != "^retrying .*" || ss[2].Level != DebugLevel || !ss[2].Expires.Equal(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("regexp suppression = %+v", ss[2])
+	}
+	if ss[3].Level != WarnLevel || !ss[3].Expires.Equal(time.Date(2025, 6, 30, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("message suppression = %+v", ss[3])
+	}
+
+	for _, bad := range []string{
+		`{"message": "a"}`,
+		`[{"regexp": "("}]`,
+		`[{"message": "a", "action": "loud"}]`,
+		`[{"message": "a", "expires": "June"}]`,
+	} {
+		if _, err := LoadSuppressions(strings.NewReader(bad)); err == nil {
+			t.Errorf("LoadSuppressions(%s) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FormatSyslog renders RFC 5424 syslog messages, with the entry's
+// fields as structured data, so relays can filter on them without
+// parsing the message:
+//
+//	<14>1 2024-01-02T03:04:05.678Z host prefix 1234 CODE [fields@32473 user="bob"] message
+//
+// The prefix is the APP-NAME and the code field, see WithCode, the
+// MSGID. The SD-ID and facility are set with SetSyslogSDID and
+// SetSyslogFacility.
+const FormatSyslog Format = "syslog"
+
+// DefaultSyslogSDID is the SD-ID of the structured data element
+// holding the fields. 32473 is the private enterprise number reserved
+// for documentation; use your own.
+const DefaultSyslogSDID = "fields@32473"
+
+// SetSyslogFacility sets the facility of FormatSyslog messages. The
+// default is 1, user-level messages.
+func SetSyslogFacility(facility int) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.facility = facility
+	out.enc = out.newEncoder()
+}
+
+// SetSyslogSDID sets the SD-ID of the structured data element holding
+// the fields in FormatSyslog messages. The default is
+// DefaultSyslogSDID.
+func SetSyslogSDID(id string) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.sdid = id
+	out.enc = out.newEncoder()
+}
+
+type syslogEncoder struct {
+	facility int
+	host     string
+	pid      string
+	sdid     string
+}
+
+func newSyslogEncoder(facility int, sdid string) *syslogEncoder {
+	host, _ := os.Hostname()
+	if sdid == "" {
+		sdid = DefaultSyslogSDID
+	}
+	return &syslogEncoder{
+		facility: facility,
+		host:     syslogName(host, 255),
+		pid:      strconv.Itoa(os.Getpid()),
+		sdid:     syslogName(sdid, 32),
+	}
+}
+
+var syslogSeverity = [FatalLevel + 1]int{
+	DebugLevel: 7,
+	InfoLevel:  6,
+	WarnLevel:  4,
+	ErrorLevel: 3,
+	FatalLevel: 2,
+}
+
+func (s *syslogEncoder) encode(b *bytes.Buffer, e *entry) {
+	sev := 6
+	if e.level >= DebugLevel && e.level <= FatalLevel {
+		sev = syslogSeverity[e.level]
+	}
+	b.WriteByte('<')
+	b.Write(strconv.AppendInt(b.AvailableBuffer(), int64(s.facility*8+sev), 10))
+	b.WriteString(">1 ")
+	b.Write(e.time.UTC().AppendFormat(b.AvailableBuffer(), "2006-01-02T15:04:05.000000Z07:00"))
+	b.WriteByte(' ')
+	b.WriteString(s.host)
+	b.WriteByte(' ')
+	b.WriteString(syslogName(e.prefix, 48))
+	b.WriteByte(' ')
+	b.WriteString(s.pid)
+	b.WriteByte(' ')
+	msgid := "-"
+	for _, f := range e.fields {
+		if f.key == CodeKey {
+			msgid = syslogName(f.stringValue(), 32)
+		}
+	}
+	b.WriteString(msgid)
+	b.WriteByte(' ')
+	s.writeSD(b, e)
+	if e.message != "" {
+		b.WriteByte(' ')
+		writeTextString(b, e.message)
+	}
+	b.WriteByte('\n')
+}
+
+// writeSD writes the structured data element, or the nil value if the
+// entry has no fields.
+func (s *syslogEncoder) writeSD(b *bytes.Buffer, e *entry) {
+	if len(e.fields) == 0 && e.caller == "" && len(e.groups) == 0 {
+		b.WriteByte('-')
+		return
+	}
+	b.WriteByte('[')
+	b.WriteString(s.sdid)
+	var v bytes.Buffer
+	param := func(key string) {
+		b.WriteByte(' ')
+		b.WriteString(syslogName(key, 32))
+		b.WriteString(`="`)
+		writeSDValue(b, v.String())
+		b.WriteByte('"')
+		v.Reset()
+	}
+	if e.caller != "" {
+		v.WriteString(e.caller)
+		param("caller")
+	}
+	if len(e.groups) > 0 {
+		v.WriteString(strings.Join(e.groups, "/"))
+		param("group")
+	}
+	for _, f := range e.fields {
+		if f.key == CodeKey {
+			continue
+		}
+		writeTextField(&v, f)
+		param(f.key)
+	}
+	b.WriteByte(']')
+}
+
+// writeSDValue writes s with '"', '\' and ']' escaped.
+func writeSDValue(b *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', ']':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// syslogName makes s a valid header field or SD-NAME: printable ASCII
+// without space, '=', ']' or '"', at most n long, or the nil value "-"
+// if empty.
+func syslogName(s string, n int) string {
+	if s == "" {
+		return "-"
+	}
+	if len(s) > n {
+		s = s[:n]
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var syslogTime = time.Date(2024, 1, 2, 3, 4, 5, 678000000, time.UTC)
+
+func TestSyslogEncoder(t *testing.T) {
+	s := &syslogEncoder{facility: 1, host: "web1", pid: "1234", sdid: DefaultSyslogSDID}
+	for _, c := range []struct {
+		name  string
+		entry entry
+		want  string
+	}{
+		{
+			"plain",
+			entry{time: syslogTime, level: InfoLevel, prefix: "api", message: "started"},
+			"<14>1 2024-01-02T03:04:05.678000Z web1 api 1234 - - started\n",
+		},
+		{
+			"fields and code",
+			entry{time: syslogTime, level: ErrorLevel, prefix: "db", message: "connect failed",
+				fields: []Field{Str(CodeKey, "DB_CONN"), Str("user", "bob"), Int("attempt", 3)}},
+			`<11>1 2024-01-02T03:04:05.678000Z web1 db 1234 DB_CONN [fields@32473 user="bob" attempt="3"] connect failed` + "\n",
+		},
+		{
+			"caller and group",
+			entry{time: syslogTime, level: DebugLevel, prefix: "job", message: "step",
+				caller: "job/run.go:12", groups: []string{"sync", "users"}},
+			`<15>1 2024-01-02T03:04:05.678000Z web1 job 1234 - [fields@32473 caller="job/run.go:12" group="sync/users"] step` + "\n",
+		},
+		{
+			"escaped values",
+			entry{time: syslogTime, level: WarnLevel, prefix: "api", message: "line one\nline two",
+				fields: []Field{Str("q", `say "hi" \ [x]`)}},
+			`<12>1 2024-01-02T03:04:05.678000Z web1 api 1234 - [fields@32473 q="say \"hi\" \\ [x\]"] line one\nline two` + "\n",
+		},
+		{
+			"names",
+			entry{time: syslogTime, level: FatalLevel, prefix: "my app", message: "",
+				fields: []Field{Str("a=b", "v"), Str(strings.Repeat("k", 40), "v")}},
+			`<10>1 2024-01-02T03:04:05.678000Z web1 my_app 1234 - [fields@32473 a_b="v" ` + strings.Repeat("k", 32) + `="v"]` + "\n",
+		},
+		{
+			"no prefix",
+			entry{time: syslogTime.In(time.FixedZone("EST", -5*3600)), level: InfoLevel, message: "m"},
+			"<14>1 2024-01-02T03:04:05.678000Z web1 - 1234 - - m\n",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var b bytes.Buffer
+			s.encode(&b, &c.entry)
+			if got := b.String(); got != c.want {
+				t.Errorf("got  %q\nwant %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSyslogFormat(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetFormat(FormatSyslog)
+	SetSyslogFacility(16)
+	SetSyslogSDID("app@99999")
+	defer func() {
+		SetSyslogFacility(1)
+		SetSyslogSDID("")
+		SetFormat(FormatText)
+		ResetOutput()
+	}()
+	NewLogger("api", false).WithField("k", "v").Warn("slow")
+
+	host, _ := os.Hostname()
+	want := " " + syslogName(host, 255) + " api " + strconv.Itoa(os.Getpid()) + ` - [app@99999 k="v"] slow` + "\n"
+	got := buf.String()
+	if !strings.HasPrefix(got, "<132>1 ") || !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, want <132>1 <time>%s", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// debugUntil is the UnixNano time until which debug is on for all
+// loggers, set by the package level TempDebug.
+var debugUntil int64
+
+// TempDebug turns on debug output for all loggers, including those
+// created with debugEnabled false, for d. It then reverts without
+// needing to be turned off, so it is safe to trigger from an admin
+// endpoint in production. A later call replaces the window; a d of 0
+// or less ends it.
+func TempDebug(d time.Duration) {
+	atomic.StoreInt64(&debugUntil, deadline(d))
+}
+
+// TempDebug turns on debug output for the logger, and loggers derived
+// from it, for d, but not for the logger it was derived from. See the
+// package level TempDebug.
+func (l *Logger) TempDebug(d time.Duration) {
+	atomic.StoreInt64(&l.ov().debugUntil, deadline(d))
+}
+
+func deadline(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return time.Now().Add(d).UnixNano()
+}
+
+func (l *Logger) tempDebug() bool {
+	until := atomic.LoadInt64(&debugUntil)
+	for ov := l.ov(); ov != nil; ov = ov.parent {
+		if u := atomic.LoadInt64(&ov.debugUntil); u > until {
+			until = u
+		}
+	}
+	return until != 0 && time.Now().UnixNano() < until
+}
+
+type debugKey struct{}
+
+// WithDebug returns a context for which FromContext returns loggers
+// with debug output on, so debugging can be enabled for one code path
+// or request.
+func WithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugKey{}, true)
+}
+
+func debugContext(ctx context.Context) bool {
+	on, _ := ctx.Value(debugKey{}).(bool)
+	return on
+}
+
+// withDebugOn returns a copy of the logger with debug forced on.
+func (l *Logger) withDebugOn() *Logger {
+	c := l.derive()
+	c.overrides.debug = 1
+	return c
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// TerminalMode controls how long text lines are fitted to the width
+// of a terminal.
+type TerminalMode int32
+
+const (
+	// TerminalOff leaves lines alone. This is the default.
+	TerminalOff TerminalMode = iota
+	// TerminalTruncate cuts lines off at the terminal width.
+	TerminalTruncate
+	// TerminalWrap breaks lines at the terminal width, indenting the
+	// continuation lines to the message column.
+	TerminalWrap
+)
+
+var terminalMode int32
+
+// SetTerminalMode sets how text lines longer than the terminal is wide
+// are handled. The caller column is dropped first, then the line is
+// truncated or wrapped as needed.
+//
+// This only applies to the text format when the output is a terminal.
+// Output to files and pipes is never changed, so shipped logs stay
+// complete. The width is taken from the terminal, or from COLUMNS if
+// it can't be queried.
+func SetTerminalMode(m TerminalMode) {
+	atomic.StoreInt32(&terminalMode, int32(m))
+}
+
+// terminalFile returns w as a file if it is a terminal.
+func terminalFile(w interface{}) *os.File {
+	f, ok := w.(*os.File)
+	if !ok {
+		return nil
+	}
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return f
+}
+
+func terminalColumns(f *os.File) int {
+	if n := terminalWidth(f); n > 0 {
+		return n
+	}
+	n, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	return n
+}
+
+// fitTerminal re-renders the entry in o.buf to fit the terminal.
+func (o *output) fitTerminal(e *entry) {
+	mode := TerminalMode(atomic.LoadInt32(&terminalMode))
+	if mode == TerminalOff || o.tty == nil || o.format != FormatText {
+		return
+	}
+	width := terminalColumns(o.tty)
+	if width <= 0 || maxLineWidth(o.buf.Bytes()) <= width {
+		return
+	}
+	if e.caller != "" {
+		caller := e.caller
+		e.caller = ""
+		o.buf.Reset()
+		o.enc.encode(&o.buf, e)
+		e.caller = caller
+		if maxLineWidth(o.buf.Bytes()) <= width {
+			return
+		}
+	}
+	lines := bytes.SplitAfter(o.buf.Bytes(), []byte("\n"))
+	var b bytes.Buffer
+	indent := messageColumn(e)
+	if indent > width/2 {
+		indent = 0
+	}
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		if mode == TerminalTruncate {
+			b.Write(cutWidth(line, width))
+			b.WriteByte('\n')
+			continue
+		}
+		first := true
+		for {
+			w := width
+			if !first {
+				b.Write(bytes.Repeat([]byte(" "), indent))
+				w -= indent
+			}
+			head := cutWidth(line, w)
+			b.Write(head)
+			b.WriteByte('\n')
+			line = line[len(head):]
+			first = false
+			if len(line) == 0 {
+				break
+			}
+		}
+	}
+	o.buf.Reset()
+	o.buf.Write(b.Bytes())
+}
+
+// messageColumn returns the width of the text columns before the
+// message.
+func messageColumn(e *entry) int {
+	n := prefixLimit + len(columnSep)
+	if e.debug {
+		n += 3 + len(columnSep)
+	}
+	if e.caller != "" {
+		n += callerLimit + len(columnSep)
+	}
+	return n
+}
+
+func maxLineWidth(b []byte) int {
+	max := 0
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if n := visibleWidth(line); n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// visibleWidth counts the runes in b, skipping ANSI color escapes.
+func visibleWidth(b []byte) int {
+	n := 0
+	for i := 0; i < len(b); {
+		if j := escapeLen(b[i:]); j > 0 {
+			i += j
+			continue
+		}
+		_, size := utf8.DecodeRune(b[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// cutWidth returns the longest prefix of b that is at most width runes
+// wide, not counting escapes. At least one rune is kept so wrapping
+// always makes progress.
+func cutWidth(b []byte, width int) []byte {
+	n := 0
+	for i := 0; i < len(b); {
+		if j := escapeLen(b[i:]); j > 0 {
+			i += j
+			continue
+		}
+		if n >= width && n > 0 {
+			return b[:i]
+		}
+		_, size := utf8.DecodeRune(b[i:])
+		i += size
+		n++
+	}
+	return b
+}
+
+// escapeLen returns the length of the "ESC [ ... m" sequence starting
+// b, or 0.
+func escapeLen(b []byte) int {
+	if len(b) < 2 || b[0] != 0x1b || b[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(b); i++ {
+		if b[i] == 'm' {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd)
+
+package log
+
+import "os"
+
+// terminalWidth returns 0 where the width can't be queried, leaving
+// COLUMNS to decide.
+func terminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,5 @@
+// Package extension holds example Encoder and Sink implementations
+// written against only the exported API of the log package, as a team
+// building its own would. Its tests, run by the log package's tests,
+// check that the API they use keeps working.
+package extension
@@ -0,0 +1,46 @@
+package extension
+
+import (
+	"bytes"
+	"testing"
+
+	log "github.com/dangersalad/go-log"
+)
+
+func TestTabEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetEncoder(TabEncoder{})
+	defer func() {
+		log.SetFormat(log.FormatText)
+		log.ResetOutput()
+	}()
+	log.NewLogger("tab", false).Log(log.WarnLevel, "disk\tlow", log.Int("free_mb", 12), log.Str("mount", "/var"))
+	if got, want := buf.String(), "warn\ttab\tdisk low\tfree_mb=12\tmount=/var\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMemorySink(t *testing.T) {
+	s := &MemorySink{}
+	remove := log.AddSink(s, nil)
+	l := log.NewLogger("mem", false)
+	l.Log(log.ErrorLevel, "charge failed", log.Str("order", "A-1"), log.Int("cents", 1250))
+	remove()
+	l.Log(log.ErrorLevel, "after remove")
+
+	rs := s.Records()
+	if len(rs) != 1 {
+		t.Fatalf("got %d records, want 1", len(rs))
+	}
+	r := rs[0]
+	if r.Level != log.ErrorLevel || r.Prefix != "mem" || r.Message != "charge failed" {
+		t.Errorf("record = %+v", r)
+	}
+	if r.Fields["order"] != "A-1" || r.Fields["cents"] != int64(1250) {
+		t.Errorf("fields = %v", r.Fields)
+	}
+	if !bytes.Contains([]byte(r.Encoded), []byte(`"msg":"charge failed"`)) {
+		t.Errorf("encoded = %q, want JSON", r.Encoded)
+	}
+}
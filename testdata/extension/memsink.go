@@ -0,0 +1,49 @@
+package extension
+
+import (
+	"sync"
+
+	log "github.com/dangersalad/go-log"
+)
+
+// MemorySink keeps the entries written to it, as a sink that sends
+// fields to a service's own API would read them.
+type MemorySink struct {
+	mu      sync.Mutex
+	entries []Record
+}
+
+// Record is an entry kept by MemorySink.
+type Record struct {
+	Level   log.Level
+	Prefix  string
+	Message string
+	Fields  map[string]interface{}
+	Encoded string
+}
+
+// WriteEntry implements log.Sink. The entry and its fields are only
+// valid during the call, so they are copied.
+func (s *MemorySink) WriteEntry(e *log.Entry, encoded []byte) error {
+	r := Record{
+		Level:   e.Level,
+		Prefix:  e.Prefix,
+		Message: e.Message,
+		Fields:  make(map[string]interface{}, len(e.Fields)),
+		Encoded: string(encoded),
+	}
+	for _, f := range e.Fields {
+		r.Fields[f.Key()] = f.Value()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, r)
+	return nil
+}
+
+// Records returns the entries written so far.
+func (s *MemorySink) Records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Record(nil), s.entries...)
+}
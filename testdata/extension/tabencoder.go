@@ -0,0 +1,33 @@
+package extension
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	log "github.com/dangersalad/go-log"
+)
+
+// TabEncoder renders entries as tab separated columns, level, prefix,
+// message and key=value fields, for tools that split on tabs.
+type TabEncoder struct{}
+
+// Encode implements log.Encoder.
+func (TabEncoder) Encode(b *bytes.Buffer, e *log.Entry) {
+	b.WriteString(e.Level.String())
+	b.WriteByte('\t')
+	b.WriteString(e.Prefix)
+	b.WriteByte('\t')
+	b.WriteString(clean(e.Message))
+	for _, f := range e.Fields {
+		fmt.Fprintf(b, "\t%s=%s", clean(f.Key()), clean(fmt.Sprint(f.Value())))
+	}
+	b.WriteByte('\n')
+}
+
+var cleaner = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+// clean keeps s to one column.
+func clean(s string) string {
+	return cleaner.Replace(s)
+}
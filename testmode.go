@@ -0,0 +1,93 @@
+package log
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxTestBuffer is how much output the test buffer keeps.
+const maxTestBuffer = 1 << 20
+
+// testBuffer holds output when running under go test without -v, so
+// logging from the package under test does not clutter test results.
+// Writes go to stdout once -v is known to be set.
+type testBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+var testOutput = &testBuffer{}
+
+// testBinary reports whether the process is a test binary built by go
+// test, which names it after the package with a .test suffix. It is
+// worked out from the name, rather than with testing.Testing, so
+// programs using the package don't link in the testing package.
+// Package variables are initialized before any init function, so the
+// output is the test buffer from the first entry on.
+var testBinary = isTestBinary(os.Args)
+
+func isTestBinary(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	name := strings.TrimSuffix(filepath.Base(args[0]), ".exe")
+	return strings.HasSuffix(name, ".test")
+}
+
+// defaultWriter is the output before SetOutput is called.
+func defaultWriter() io.Writer {
+	if testBinary {
+		return testOutput
+	}
+	return os.Stdout
+}
+
+// defaultTerminal is the terminal of the default output, if it is one.
+func defaultTerminal() *os.File {
+	if testBinary {
+		return nil
+	}
+	return terminalFile(os.Stdout)
+}
+
+// verbose reports whether go test is running with -v. It is false
+// until the testing flags are registered.
+func verbose() bool {
+	f := flag.Lookup("test.v")
+	return f != nil && f.Value.String() == "true"
+}
+
+func (t *testBuffer) Write(p []byte) (int, error) {
+	if verbose() {
+		return os.Stdout.Write(p)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if n := len(t.buf) - maxTestBuffer; n > 0 {
+		t.buf = append(t.buf[:0], t.buf[n:]...)
+	}
+	return len(p), nil
+}
+
+// DumpTestOutput writes the output held back while running under go
+// test to w and clears it, returning the number of bytes written.
+//
+// When a test binary runs without -v, output goes to a buffer, keeping
+// the last 1MB, instead of stdout. Call this when a test fails to see
+// what was logged, or use logtest.Quiet, which does that for a single
+// test. SetOutput turns the buffering off and ResetOutput back on.
+func DumpTestOutput(w io.Writer) (int, error) {
+	testOutput.mu.Lock()
+	b := testOutput.buf
+	testOutput.buf = nil
+	testOutput.mu.Unlock()
+	if len(b) == 0 {
+		return 0, nil
+	}
+	return w.Write(b)
+}
@@ -0,0 +1,63 @@
+package log
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestIsTestBinary(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"/tmp/go-build123/b001/log.test"}, true},
+		{[]string{`C:\Temp\go-build\log.test.exe`, "-test.v"}, true},
+		{[]string{"./server"}, false},
+		{[]string{"/usr/bin/latest"}, false},
+		{[]string{"server.exe"}, false},
+		{nil, false},
+	} {
+		if got := isTestBinary(c.args); got != c.want {
+			t.Errorf("isTestBinary(%q) = %v, want %v", c.args, got, c.want)
+		}
+	}
+	if !testBinary {
+		t.Error("the test binary was not detected")
+	}
+}
+
+func TestNoTestingImport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go list")
+	}
+	out, err := exec.Command("go", "list", "-deps", ".").Output()
+	if err != nil {
+		t.Skipf("go list: %v", err)
+	}
+	for _, dep := range strings.Fields(string(out)) {
+		if dep == "testing" {
+			t.Fatal("the package imports testing, which registers its flags in every program")
+		}
+	}
+}
+
+const envReportChildEnv = "LOG_TEST_ENV_REPORT"
+
+// TestEnvReportBuffered checks that the startup report about invalid
+// LOG_* variables goes to the test buffer like other output.
+func TestEnvReportBuffered(t *testing.T) {
+	if os.Getenv(envReportChildEnv) != "" {
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEnvReportBuffered$")
+	cmd.Env = append(os.Environ(), envReportChildEnv+"=1", "LOG_LEVEL=loud")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "LOG_LEVEL") {
+		t.Errorf("startup report escaped the test buffer: %q", out)
+	}
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+type checkpointsKey struct{}
+
+// checkpoints records the time since the start of a request at named
+// points.
+type checkpoints struct {
+	mu    sync.Mutex
+	start time.Time
+	marks []Field
+}
+
+// Checkpoint records the time elapsed since the start of the request
+// under name, for requests served by HTTPHandler with WithTimings. The
+// elapsed times are added as fields to the access log entry. It does
+// nothing for other contexts.
+//
+//	rows, err := db.QueryContext(r.Context(), q)
+//	log.Checkpoint(r.Context(), "query")
+func Checkpoint(ctx context.Context, name string) {
+	cps, ok := ctx.Value(checkpointsKey{}).(*checkpoints)
+	if !ok {
+		return
+	}
+	d := time.Since(cps.start)
+	cps.mu.Lock()
+	defer cps.mu.Unlock()
+	cps.marks = append(cps.marks, Any(name, formatDuration(d)))
+}
+
+func (cps *checkpoints) fields() []Field {
+	cps.mu.Lock()
+	defer cps.mu.Unlock()
+	return append([]Field(nil), cps.marks...)
+}
+
+// clientTrace times the phases of an outbound request.
+type clientTrace struct {
+	mu sync.Mutex
+	start, dnsStart, dnsDone, connStart, connDone,
+	tlsStart, tlsDone, firstByte time.Time
+}
+
+func (t *clientTrace) mark(at *time.Time) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.IsZero() {
+		*at = now
+	}
+}
+
+func (t *clientTrace) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		ConnectStart:         func(string, string) { t.mark(&t.connStart) },
+		ConnectDone:          func(string, string, error) { t.mark(&t.connDone) },
+		TLSHandshakeStart:    func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.mark(&t.tlsDone) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	}
+}
+
+// fields returns the duration of each phase that happened. A reused
+// connection has no dns, connect or tls phase.
+func (t *clientTrace) fields() []Field {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var fs []Field
+	span := func(key string, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() {
+			fs = append(fs, Any(key, formatDuration(to.Sub(from))))
+		}
+	}
+	span("dns", t.dnsStart, t.dnsDone)
+	span("connect", t.connStart, t.connDone)
+	span("tls", t.tlsStart, t.tlsDone)
+	span("ttfb", t.start, t.firstByte)
+	return fs
+}
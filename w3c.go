@@ -0,0 +1,140 @@
+package log
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultW3CFields are the fields written by WithW3CLog if none are
+// given.
+var DefaultW3CFields = []string{
+	"date", "time", "c-ip", "cs-method", "cs-uri-stem", "cs-uri-query",
+	"sc-status", "sc-bytes", "time-taken", "cs(User-Agent)",
+}
+
+// WithW3CLog writes access log entries to w in the W3C Extended Log
+// File Format instead of logging them, for analytics tools that read
+// that format. The #Version, #Date and #Fields directives are written
+// before the first entry.
+//
+// fields selects the columns, DefaultW3CFields if none are given. The
+// supported fields are date, time, c-ip, cs-method, cs-uri,
+// cs-uri-stem, cs-uri-query, cs-version, cs-host, sc-status, sc-bytes,
+// time-taken (in seconds) and cs(Header) for any request header. Other
+// fields are written as "-". The query redaction and path mask options
+// apply.
+//
+// Only HTTPHandler supports this option.
+func WithW3CLog(w io.Writer, fields ...string) HTTPOption {
+	if len(fields) == 0 {
+		fields = DefaultW3CFields
+	}
+	l := &w3cLog{w: w, fields: append([]string(nil), fields...)}
+	return func(o *httpOptions) {
+		o.w3c = l
+	}
+}
+
+type w3cLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	fields []string
+	header bool
+	buf    []byte
+}
+
+// w3cRequest is what is known about a request once it is served.
+type w3cRequest struct {
+	r      *http.Request
+	start  time.Time
+	status int
+	bytes  int64
+	took   time.Duration
+}
+
+func (l *w3cLog) write(o *httpOptions, req w3cRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.buf[:0]
+	if !l.header {
+		b = append(b, "#Version: 1.0\n#Date: "...)
+		b = req.start.UTC().AppendFormat(b, "2006-01-02 15:04:05")
+		b = append(b, "\n#Fields: "...)
+		b = append(b, strings.Join(l.fields, " ")...)
+		b = append(b, '\n')
+		l.header = true
+	}
+	for i, f := range l.fields {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = appendW3CValue(b, o, f, req)
+	}
+	b = append(b, '\n')
+	l.buf = b
+	if _, err := l.w.Write(b); err != nil {
+		reportError(&SinkError{Sink: "w3c", Err: err})
+	}
+}
+
+func appendW3CValue(b []byte, o *httpOptions, field string, req w3cRequest) []byte {
+	r := req.r
+	switch field {
+	case "date":
+		return req.start.UTC().AppendFormat(b, "2006-01-02")
+	case "time":
+		return req.start.UTC().AppendFormat(b, "15:04:05")
+	case "c-ip":
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return appendW3CString(b, host)
+	case "cs-method":
+		return appendW3CString(b, r.Method)
+	case "cs-uri":
+		return appendW3CString(b, o.logURL(r.URL).RequestURI())
+	case "cs-uri-stem":
+		return appendW3CString(b, o.logURL(r.URL).EscapedPath())
+	case "cs-uri-query":
+		return appendW3CString(b, o.logURL(r.URL).RawQuery)
+	case "cs-version":
+		return appendW3CString(b, r.Proto)
+	case "cs-host":
+		return appendW3CString(b, r.Host)
+	case "sc-status":
+		return strconv.AppendInt(b, int64(req.status), 10)
+	case "sc-bytes":
+		return strconv.AppendInt(b, req.bytes, 10)
+	case "time-taken":
+		return strconv.AppendFloat(b, req.took.Seconds(), 'f', 3, 64)
+	}
+	if strings.HasPrefix(field, "cs(") && strings.HasSuffix(field, ")") {
+		return appendW3CString(b, r.Header.Get(field[3:len(field)-1]))
+	}
+	return append(b, '-')
+}
+
+// appendW3CString appends s, or "-" if it is empty, with spaces
+// replaced by '+' and control characters dropped, so it stays one
+// field.
+func appendW3CString(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ' ':
+			b = append(b, '+')
+		case c < 0x20 || c == 0x7f:
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestW3CLog(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, c := range []struct {
+		name   string
+		fields []string
+		opts   []HTTPOption
+		target string
+		want   string
+	}{
+		{
+			name:   "default fields",
+			target: "/a/b?x=1&y=2",
+			want:   "2024-01-02 03:04:05 192.0.2.1 GET /a/b x=1&y=2 200 512 0.250 curl/8.0+(x86_64)",
+		},
+		{
+			name:   "empty values",
+			fields: []string{"cs-uri-query", "cs(Referer)", "sc-bytes", "s-sitename"},
+			target: "/",
+			want:   "- - 512 -",
+		},
+		{
+			name:   "request fields",
+			fields: []string{"cs-uri", "cs-version", "cs-host", "sc-status"},
+			target: "/a%20b?q=1",
+			want:   "/a%20b?q=1 HTTP/1.1 example.com 200",
+		},
+		{
+			name:   "redaction",
+			fields: []string{"cs-uri-stem", "cs-uri-query"},
+			opts:   []HTTPOption{WithQueryDenylist("token"), WithPathMask(regexp.MustCompile(`[0-9]+`))},
+			target: "/users/42/posts?token=s3cret&page=2",
+			want:   "/users/" + Redacted + "/posts token=" + Redacted + "&page=2",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			o := newHTTPOptions(append(c.opts, WithW3CLog(&buf, c.fields...)))
+			r := httptest.NewRequest("GET", "http://example.com"+c.target, nil)
+			r.RemoteAddr = "192.0.2.1:5555"
+			r.Header.Set("User-Agent", "curl/8.0 (x86_64)\n")
+			req := w3cRequest{r: r, start: start, status: 200, bytes: 512, took: 250 * time.Millisecond}
+			o.w3c.write(o, req)
+			o.w3c.write(o, req)
+
+			fields := c.fields
+			if fields == nil {
+				fields = DefaultW3CFields
+			}
+			want := "#Version: 1.0\n#Date: 2024-01-02 03:04:05\n#Fields: " + strings.Join(fields, " ") + "\n" +
+				c.want + "\n" + c.want + "\n"
+			if got := buf.String(); got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestW3CLogHandler(t *testing.T) {
+	var buf, w3c syncBuffer
+	SetOutput(&buf)
+	defer ResetOutput()
+
+	h := HTTPHandler(http.HandlerFunc(statusHandler), NewLogger("w3c", false), nil,
+		WithW3CLog(&w3c, "cs-method", "cs-uri-stem", "sc-status", "sc-bytes"))
+	for _, target := range []string{"/?status=200", "/missing?status=404"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	lines := strings.Split(w3c.String(), "\n")
+	if len(lines) != 6 || lines[2] != "#Fields: cs-method cs-uri-stem sc-status sc-bytes" {
+		t.Fatalf("w3c log = %q", w3c.String())
+	}
+	if lines[3] != "GET / 200 0" || lines[4] != "GET /missing 404 0" {
+		t.Errorf("entries = %q", lines[3:5])
+	}
+	if buf.String() != "" {
+		t.Errorf("access entries were logged too: %q", buf.String())
+	}
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Watch warns about a hung operation with the logger carried by ctx.
+// See Logger.Watch.
+func Watch(ctx context.Context, name string, warnAfter time.Duration) (done func()) {
+	return FromContext(ctx).watch(ctx, name, warnAfter, getCaller())
+}
+
+// Watch logs a warning if the operation has not finished warnAfter
+// after it started, for code that otherwise logs nothing until it is
+// done. Call done when the operation finishes:
+//
+//	done := logger.Watch(ctx, "flush index", 30*time.Second)
+//	defer done()
+//
+// The warning is repeated each time the elapsed time doubles, at the
+// error level once the operation has taken four times warnAfter. If
+// it was warned about, done logs that the operation finished, with how
+// long it took. Watching stops when done is called or ctx is done.
+// Entries have an op field with the name and an elapsed field, and the
+// caller of Watch as their caller.
+//
+// A warnAfter of 0 or less turns the watch off: nothing is logged and
+// done does nothing.
+func (l *Logger) Watch(ctx context.Context, name string, warnAfter time.Duration) (done func()) {
+	return l.watch(ctx, name, warnAfter, getCaller())
+}
+
+func (l *Logger) watch(ctx context.Context, name string, warnAfter time.Duration, site string) (done func()) {
+	if warnAfter <= 0 {
+		return func() {}
+	}
+	l = l.with(Str(OpKey, name))
+	start := time.Now()
+	var (
+		mu     sync.Mutex
+		warned bool
+		over   bool
+	)
+	stop := make(chan struct{})
+	go func() {
+		wait := warnAfter
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			mu.Lock()
+			if over {
+				mu.Unlock()
+				return
+			}
+			warned = true
+			mu.Unlock()
+			elapsed := time.Since(start)
+			lvl := WarnLevel
+			if elapsed >= 4*warnAfter {
+				lvl = ErrorLevel
+			}
+			l.logFrom(site, lvl, name+" still running", Dur("elapsed", elapsed))
+			t.Reset(wait)
+			wait *= 2
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			mu.Lock()
+			over = true
+			w := warned
+			mu.Unlock()
+			if w {
+				l.logFrom(site, InfoLevel, name+" finished", Dur("elapsed", time.Since(start)))
+			}
+		})
+	}
+}
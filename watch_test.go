@@ -0,0 +1,43 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	defer ResetOutput()
+	l := NewLogger("watch", false)
+
+	done := l.Watch(context.Background(), "flush", 10*time.Millisecond)
+	time.Sleep(25 * time.Millisecond)
+	done()
+	done()
+	out := buf.String()
+	if !strings.Contains(out, "flush still running") {
+		t.Errorf("output = %q, want a warning", out)
+	}
+	if n := strings.Count(out, "flush finished"); n != 1 {
+		t.Errorf("output has %d finished entries, want 1: %q", n, out)
+	}
+}
+
+func TestWatchDisabled(t *testing.T) {
+	var buf syncBuffer
+	SetOutput(&buf)
+	defer ResetOutput()
+	l := NewLogger("watch", false)
+
+	for _, d := range []time.Duration{0, -time.Second} {
+		done := l.Watch(context.Background(), "flush", d)
+		time.Sleep(10 * time.Millisecond)
+		done()
+	}
+	if out := buf.String(); out != "" {
+		t.Errorf("Watch with warnAfter <= 0 logged %q, want nothing", out)
+	}
+}